@@ -17,6 +17,7 @@ require (
 	github.com/tinylib/msgp v1.1.8
 	go.uber.org/atomic v1.11.0
 	golang.org/x/sync v0.5.0
+	golang.org/x/sys v0.16.0
 )
 
 require (
@@ -61,7 +62,6 @@ require (
 	github.com/tklauser/numcpus v0.6.1 // indirect
 	golang.org/x/crypto v0.17.0 // indirect
 	golang.org/x/exp v0.0.0-20231110203233-9a3e6036ecaa // indirect
-	golang.org/x/sys v0.16.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	google.golang.org/protobuf v1.28.1 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ReasonAdminRequest is the shutdown reason logged when a POST to the admin
+// endpoint's /shutdown carries no reason.
+const ReasonAdminRequest = "admin-request"
+
+// adminStatus is the JSON answer of GET /status.
+type adminStatus struct {
+	Uptime        string  `json:"uptime"`
+	UptimeSeconds float64 `json:"uptime_seconds"`
+	ShuttingDown  bool    `json:"shutting_down"`
+	Paused        bool    `json:"paused"`
+	Interrupts    int64   `json:"interrupts"`
+}
+
+// AdminHandler returns the handler served by ServeAdmin.
+func AdminHandler() http.Handler {
+	control := func(fn func(r *http.Request) bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.Header().Set("Allow", http.MethodPost)
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			if InterruptRequested(shutdownDone()) || !fn(r) {
+				http.Error(w, "shutdown in progress", http.StatusConflict)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/shutdown", control(func(r *http.Request) bool {
+		body, _ := io.ReadAll(io.LimitReader(r.Body, 1024))
+		reason := strings.TrimSpace(string(body))
+		if reason == "" {
+			reason = ReasonAdminRequest
+		}
+		return requestShutdown(reason, "remote", r.RemoteAddr)
+	}))
+	mux.Handle("/pause", control(func(*http.Request) bool {
+		RequestPause()
+		return true
+	}))
+	mux.Handle("/resume", control(func(*http.Request) bool {
+		RequestResume()
+		return true
+	}))
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		stats := InterruptStats()
+		status := adminStatus{
+			ShuttingDown: InterruptRequested(shutdownDone()),
+			Paused:       IsPaused(),
+		}
+		uptime := time.Since(stats.ProcessStart)
+		status.Uptime, status.UptimeSeconds = uptime.Round(time.Second).String(), uptime.Seconds()
+		for _, n := range stats.Signals {
+			status.Interrupts += n
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	})
+	return mux
+}
+
+// ServeAdmin starts a minimal control server on addr exposing
+//
+//	POST /shutdown  requests shutdown, with the request body as the reason
+//	POST /pause     RequestPause
+//	POST /resume    RequestResume
+//	GET  /status    JSON with uptime, uptime_seconds, shutting_down, paused
+//	                and interrupts, the number of interrupt signals received
+//
+// The POST endpoints answer 202 Accepted, or 409 Conflict once shutdown has
+// begun.  There is no authentication: addr is either a host:port, where an
+// empty host means 127.0.0.1, or the path of a unix socket, which is only
+// ever accessible to the owner.  A socket left behind by a crashed process is
+// replaced.  The server keeps answering until the shutdown handlers have
+// finished and then shuts itself down like StartDebugServer's, letting open
+// requests such as the triggering POST /shutdown complete; it can also be
+// closed directly.  Listen errors are returned.  Nothing is started if addr
+// is empty, in which case both return values are nil.
+func ServeAdmin(addr string) (io.Closer, error) {
+	if addr == "" {
+		return nil, nil
+	}
+
+	ln, err := listenAdmin(addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{Handler: AdminHandler()}
+	go srv.Serve(ln)
+	shutdownAfterHandlers(srv)
+	return srv, nil
+}
+
+// listenAdmin listens on addr as described for ServeAdmin.
+func listenAdmin(addr string) (net.Listener, error) {
+	if strings.ContainsAny(addr, `/\`) {
+		removeStaleSocket(addr)
+		ln, err := listenUnixPrivate(addr)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Chmod(addr, 0o600); err != nil {
+			ln.Close()
+			return nil, err
+		}
+		return ln, nil
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return net.Listen("tcp", net.JoinHostPort(host, port))
+}
+
+// removeStaleSocket removes the unix socket at addr if nothing accepts
+// connections on it, as is the case after a crash.  Anything else at addr is
+// left for Listen to fail on.
+func removeStaleSocket(addr string) {
+	fi, err := os.Lstat(addr)
+	if err != nil || fi.Mode()&os.ModeSocket == 0 {
+		return
+	}
+	c, err := net.Dial("unix", addr)
+	if err == nil {
+		c.Close()
+		return
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		logger().Info("removing stale admin socket", "path", addr)
+		os.Remove(addr)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package utils
+
+import "net"
+
+// listenUnixPrivate listens on the unix socket addr.  There is no umask here;
+// ServeAdmin restricts the socket afterwards.
+func listenUnixPrivate(addr string) (net.Listener, error) {
+	return net.Listen("unix", addr)
+}
@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHandler(t *testing.T) {
+	resetSignals(t)
+	srv := httptest.NewServer(AdminHandler())
+	defer srv.Close()
+
+	post := func(path, body string) int {
+		resp, err := http.Post(srv.URL+path, "text/plain", strings.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	status := func() adminStatus {
+		resp, err := http.Get(srv.URL + "/status")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var s adminStatus
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&s))
+		return s
+	}
+
+	resp, err := http.Get(srv.URL + "/shutdown")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	require.False(t, status().ShuttingDown)
+
+	require.Equal(t, http.StatusAccepted, post("/pause", ""))
+	require.True(t, status().Paused)
+	require.Equal(t, http.StatusAccepted, post("/resume", ""))
+	require.False(t, status().Paused)
+
+	events, sub := SubscribeInterrupt(32)
+	defer sub.Unsubscribe()
+	StartInterrupteListener()
+
+	// Only one of many concurrent requests triggers shutdown.
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		codes = make(map[int]int)
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code := post("/shutdown", "maintenance")
+			mu.Lock()
+			codes[code]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, map[int]int{http.StatusAccepted: 1, http.StatusConflict: 19}, codes)
+	select {
+	case ev := <-events:
+		require.Equal(t, "maintenance", ev.Reason)
+		require.False(t, ev.Repeated)
+	case <-time.After(time.Second):
+		t.Fatal("shutdown request not delivered")
+	}
+
+	require.Equal(t, http.StatusConflict, post("/pause", ""))
+	s := status()
+	require.True(t, s.ShuttingDown)
+	require.False(t, s.Paused)
+	require.Positive(t, s.UptimeSeconds)
+}
+
+func TestServeAdmin(t *testing.T) {
+	resetSignals(t)
+
+	ln, err := listenAdmin(":0")
+	require.NoError(t, err)
+	defer ln.Close()
+	require.True(t, ln.Addr().(*net.TCPAddr).IP.IsLoopback())
+
+	// The address is taken, which is an error rather than fatal.
+	_, err = ServeAdmin(ln.Addr().String())
+	require.Error(t, err)
+
+	closer, err := ServeAdmin("")
+	require.NoError(t, err)
+	require.Nil(t, closer)
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	path := filepath.Join(t.TempDir(), "admin.sock")
+
+	// A socket that is still served is not replaced, a stale one is.
+	other, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	_, err = ServeAdmin(path)
+	require.Error(t, err)
+	other.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, other.Close())
+	_, err = os.Stat(path)
+	require.NoError(t, err)
+
+	closer, err = ServeAdmin(path)
+	require.NoError(t, err)
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return new(net.Dialer).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Post("http://admin/shutdown", "text/plain", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	// The server closes itself once the shutdown handlers are done.
+	WaitForShutdown()
+	require.Eventually(t, func() bool {
+		_, err := client.Get("http://admin/status")
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, closer.Close())
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package utils
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenUnixPrivate listens on the unix socket addr, which is never
+// accessible to anyone but the owner: the umask applies when the socket is
+// created.  The umask is process wide, but 077 only takes away group and
+// other permissions from files created meanwhile.
+func listenUnixPrivate(addr string) (net.Listener, error) {
+	old := syscall.Umask(0o077)
+	defer syscall.Umask(old)
+	return net.Listen("unix", addr)
+}
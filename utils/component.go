@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Component is a part of the program with a start/stop lifecycle, run by
+// RunUntilInterrupt.
+type Component interface {
+	// Start starts the component and returns once it is running.  ctx is
+	// cancelled when shutdown begins, which must make a Start that is
+	// still blocked return.
+	Start(ctx context.Context) error
+
+	// Stop stops the started component.  ctx expires after the stop
+	// timeout given to RunUntilInterrupt.
+	Stop(ctx context.Context) error
+
+	Name() string
+}
+
+// Failer is implemented by a Component whose Start returns while it keeps
+// running in the background, so that RunUntilInterrupt notices when it dies.
+type Failer interface {
+	// Failed is called once after Start succeeds.  The channel receives
+	// the error that made the running component fail, or is closed if it
+	// stopped on its own; a nil channel never fires.
+	Failed() <-chan error
+}
+
+// RunUntilInterrupt starts components one after the other and keeps them
+// running until an interrupt signal or shutdown request arrives or ctx is
+// done.  It then stops every started component in reverse order, each with
+// stopTimeout (no limit if stopTimeout <= 0), and returns the errors of all
+// of them, each prefixed with the component's name.
+//
+// If a Start fails, the components after it are never started and the ones
+// before it are stopped right away; the returned error includes the failure.
+// A started component that implements Failer and reports a failure is
+// handled the same way, with the components started so far stopped in
+// reverse order.  Failures of components without Failer go unnoticed, so
+// their Start must not return until they are fully running.  A component
+// whose Start was interrupted by shutdown counts as started, so it is
+// stopped too.  A Stop that times out is abandoned.
+func RunUntilInterrupt(ctx context.Context, stopTimeout time.Duration, components ...Component) error {
+	ctx, cancel := WithInterrupt(ctx)
+	defer cancel()
+
+	var (
+		errs     []error
+		started  []Component
+		watchers sync.WaitGroup
+		failures = make(chan error, len(components))
+	)
+	for _, c := range components {
+		if ctx.Err() != nil {
+			break
+		}
+		err := c.Start(ctx)
+		if err != nil && ctx.Err() == nil {
+			errs = append(errs, fmt.Errorf("start %s: %w", c.Name(), err))
+			break
+		}
+		started = append(started, c)
+		logShutdownAt(logger().Info, "component started", "phase", "components", "handler", c.Name())
+		if f, ok := c.(Failer); ok && err == nil {
+			watchers.Add(1)
+			go func(name string, failed <-chan error) {
+				defer watchers.Done()
+				watchFailure(ctx, cancel, name, failed, failures)
+			}(c.Name(), f.Failed())
+		}
+	}
+	if len(errs) == 0 {
+		<-ctx.Done()
+	}
+	cancel()
+	watchers.Wait()
+	close(failures)
+	for err := range failures {
+		errs = append(errs, err)
+	}
+
+	for i := len(started) - 1; i >= 0; i-- {
+		c := started[i]
+		start := time.Now()
+		if err := runShutdownHandler(shutdownHandler{name: c.Name(), timeout: stopTimeout, fn: c.Stop}); err != nil {
+			logShutdownAt(logger().Error, "component stop failed", "phase", "components", "handler", c.Name(),
+				"duration", time.Since(start), "err", err)
+			errs = append(errs, fmt.Errorf("stop %s: %w", c.Name(), err))
+			continue
+		}
+		logShutdownAt(logger().Info, "component stopped", "phase", "components", "handler", c.Name(),
+			"duration", time.Since(start))
+	}
+	return errors.Join(errs...)
+}
+
+// watchFailure reports to failures, and cancels ctx, if the named component
+// fails before ctx is done.
+func watchFailure(ctx context.Context, cancel context.CancelFunc, name string, failed <-chan error, failures chan<- error) {
+	select {
+	case err, ok := <-failed:
+		if !ok || err == nil {
+			err = errors.New("stopped unexpectedly")
+		}
+		logShutdownAt(logger().Error, "component failed", "phase", "components", "handler", name, "err", err)
+		failures <- fmt.Errorf("run %s: %w", name, err)
+		cancel()
+	case <-ctx.Done():
+	}
+}
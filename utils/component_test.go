@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testComponent records its Start and Stop calls in a shared log.
+type testComponent struct {
+	name  string
+	start func(ctx context.Context) error // nil starts successfully
+	stop  func(ctx context.Context) error // nil stops successfully
+	fail  error                           // reported through Failed once started
+	log   *callLog
+	// failed is buffered, so a Start that succeeds can report fail.
+	failed chan error
+}
+
+type callLog struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (l *callLog) add(call string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls = append(l.calls, call)
+}
+
+func (c *testComponent) Name() string { return c.name }
+
+func (c *testComponent) Start(ctx context.Context) error {
+	c.log.add("start " + c.name)
+	if c.start != nil {
+		if err := c.start(ctx); err != nil {
+			return err
+		}
+	}
+	if c.fail != nil {
+		c.failed <- c.fail
+	}
+	return nil
+}
+
+func (c *testComponent) Failed() <-chan error { return c.failed }
+
+func (c *testComponent) Stop(ctx context.Context) error {
+	c.log.add("stop " + c.name)
+	if c.stop == nil {
+		return nil
+	}
+	return c.stop(ctx)
+}
+
+func TestRunUntilInterrupt(t *testing.T) {
+	fail := func(context.Context) error { return errors.New("boom") }
+	interrupt := func(context.Context) error {
+		go SimulateInterrupt(os.Interrupt)
+		return nil
+	}
+	blockUntilInterrupted := func(ctx context.Context) error {
+		go SimulateInterrupt(os.Interrupt)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	hang := func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}
+
+	tests := []struct {
+		name   string
+		starts []func(ctx context.Context) error
+		stops  []func(ctx context.Context) error
+		fails  []error
+		calls  []string
+		err    []string
+	}{
+		{
+			name:   "startup failure",
+			starts: []func(ctx context.Context) error{nil, fail, nil},
+			calls:  []string{"start a", "start b", "stop a"},
+			err:    []string{"start b: boom"},
+		},
+		{
+			name:   "interrupt",
+			starts: []func(ctx context.Context) error{nil, nil, interrupt},
+			calls:  []string{"start a", "start b", "start c", "stop c", "stop b", "stop a"},
+		},
+		{
+			name:   "start blocks until interrupted",
+			starts: []func(ctx context.Context) error{nil, blockUntilInterrupted, nil},
+			calls:  []string{"start a", "start b", "stop b", "stop a"},
+		},
+		{
+			name:   "failure after start",
+			starts: []func(ctx context.Context) error{nil, nil, nil},
+			fails:  []error{nil, nil, errors.New("crashed")},
+			calls:  []string{"start a", "start b", "start c", "stop c", "stop b", "stop a"},
+			err:    []string{"run c: crashed"},
+		},
+		{
+			name:   "stop timeout",
+			starts: []func(ctx context.Context) error{nil, nil, interrupt},
+			stops:  []func(ctx context.Context) error{fail, hang, nil},
+			calls:  []string{"start a", "start b", "start c", "stop c", "stop b", "stop a"},
+			err:    []string{"stop b: timed out", "stop a: boom"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetSignals(t)
+
+			log := new(callLog)
+			var components []Component
+			for i, name := range []string{"a", "b", "c"} {
+				c := &testComponent{name: name, start: tt.starts[i], log: log}
+				if tt.stops != nil {
+					c.stop = tt.stops[i]
+				}
+				if tt.fails != nil && tt.fails[i] != nil {
+					c.fail, c.failed = tt.fails[i], make(chan error, 1)
+				}
+				components = append(components, c)
+			}
+
+			start := time.Now()
+			err := RunUntilInterrupt(context.Background(), 50*time.Millisecond, components...)
+			require.Less(t, time.Since(start), time.Second)
+			require.Equal(t, tt.calls, log.calls)
+			if tt.err == nil {
+				require.NoError(t, err)
+			}
+			for _, msg := range tt.err {
+				require.ErrorContains(t, err, msg)
+			}
+		})
+	}
+}
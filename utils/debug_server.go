@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/metrics/prometheus"
+)
+
+// StartDebugServer starts an internal HTTP server on addr exposing
+//
+//	/readyz   ReadinessHandler
+//	/livez    200 while the process is serving
+//	/metrics  go-ethereum's metrics registry, only if metrics.Enabled
+//
+// The server keeps serving while the shutdown handlers run, so /readyz reports
+// the shutdown and /metrics stays scrapable, and shuts itself down once they
+// have finished, waiting at most serverShutdownTimeout for open
+// requests.  The returned server, whose Addr is the address listened on, can
+// also be closed directly.  Nothing is started if addr is empty, in which case
+// both return values are nil.
+func StartDebugServer(addr string) (*http.Server, error) {
+	if addr == "" {
+		return nil, nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/readyz", ReadinessHandler())
+	mux.HandleFunc("/livez", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	if metrics.Enabled {
+		mux.Handle("/metrics", prometheus.Handler(metrics.DefaultRegistry))
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{Addr: ln.Addr().String(), Handler: mux}
+	go srv.Serve(ln)
+	shutdownAfterHandlers(srv)
+	return srv, nil
+}
+
+// serverShutdownTimeout bounds how long the debug and admin servers wait for
+// open requests once the shutdown handlers have finished.
+const serverShutdownTimeout = 5 * time.Second
+
+// shutdownAfterHandlers shuts srv down gracefully once the shutdown handlers
+// have finished, closing it if that takes longer than serverShutdownTimeout.
+func shutdownAfterHandlers(srv *http.Server) {
+	finished := startShutdownHandlers()
+	go func() {
+		<-finished
+		ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		if srv.Shutdown(ctx) != nil {
+			srv.Close()
+		}
+	}()
+}
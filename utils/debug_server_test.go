@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartDebugServer(t *testing.T) {
+	resetSignals(t)
+
+	srv, err := StartDebugServer("")
+	require.NoError(t, err)
+	require.Nil(t, srv)
+
+	srv, err = StartDebugServer("127.0.0.1:0")
+	require.NoError(t, err)
+	defer srv.Close()
+	get := func(path string) (int, string) {
+		resp, err := http.Get("http://" + srv.Addr + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	code, _ := get("/livez")
+	require.Equal(t, http.StatusOK, code)
+	code, body := get("/readyz")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Contains(t, body, "starting")
+	MarkReady()
+	code, _ = get("/readyz")
+	require.Equal(t, http.StatusOK, code)
+
+	// While the shutdown handlers run the server still answers, reporting
+	// the shutdown.
+	release := make(chan struct{})
+	require.NoError(t, RegisterShutdownHandler("block", 0, 0, func(context.Context) error {
+		<-release
+		return nil
+	}))
+	RequestShutdown("test")
+	code, body = get("/readyz")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Contains(t, body, "shutdown")
+	code, _ = get("/livez")
+	require.Equal(t, http.StatusOK, code)
+
+	close(release)
+	WaitForShutdownComplete(context.Background())
+	require.Eventually(t, func() bool {
+		_, err := http.Get("http://" + srv.Addr + "/livez")
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+}
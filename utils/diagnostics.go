@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"time"
+)
+
+// diagnosticsProfiles are the pprof profiles written by DumpDiagnostics.
+var diagnosticsProfiles = []string{"goroutine", "heap", "allocs"}
+
+var (
+	diagnosticsMu      sync.Mutex
+	diagnosticsDir     string
+	diagnosticsSig     = diagnosticsSignal
+	diagnosticsSignals chan os.Signal // registered with the notifier, if any
+
+	// dumpMu makes sure only one dump is written at a time.
+	dumpMu sync.Mutex
+)
+
+// SetDiagnosticsSignal replaces the signal used by EnableDiagnosticsSignal,
+// which is SIGVTALRM on Unix since SIGUSR1 and SIGUSR2 pause and resume.  It
+// must be called before EnableDiagnosticsSignal.
+func SetDiagnosticsSignal(sig os.Signal) error {
+	diagnosticsMu.Lock()
+	defer diagnosticsMu.Unlock()
+
+	if diagnosticsSignals != nil {
+		return errors.New("diagnostics signal already enabled")
+	}
+	diagnosticsSig = sig
+	return nil
+}
+
+// EnableDiagnosticsSignal makes the diagnostics signal (see
+// SetDiagnosticsSignal) write a diagnostics dump to dir, which is created if
+// necessary.  Dumps are written on their own goroutine, so they never delay
+// interrupt handling, and a signal that arrives while a dump is being written
+// is folded into a single further dump.  On Windows, or with signal handling
+// disabled, there is no signal and only DumpDiagnostics writes to dir.
+// Calling it again only changes the directory.
+func EnableDiagnosticsSignal(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	diagnosticsMu.Lock()
+	defer diagnosticsMu.Unlock()
+
+	diagnosticsDir = dir
+	if diagnosticsSignals != nil || diagnosticsSig == nil || signalHandlingDisabled.Load() {
+		return nil
+	}
+
+	c := make(chan os.Signal, 1)
+	signalsMu.Lock()
+	notifier.Notify(c, diagnosticsSig)
+	signalsMu.Unlock()
+	diagnosticsSignals = c
+
+	goWorker(func(quit <-chan struct{}) {
+		for {
+			select {
+			case sig := <-c:
+				logger().Info("received diagnostics signal", "sig", signalName(sig))
+				DumpDiagnostics()
+			case <-quit:
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// DumpDiagnostics writes goroutine, heap and allocs profiles, a
+// runtime.MemStats summary and the lines kept by WithLogBuffer (empty unless
+// it is on) to the directory given to EnableDiagnosticsSignal, all named
+// after the current time, and returns the paths written.  A file
+// that can't be written is logged and skipped, and the errors are returned
+// together.  Concurrent calls are serialized.
+func DumpDiagnostics() ([]string, error) {
+	diagnosticsMu.Lock()
+	dir := diagnosticsDir
+	diagnosticsMu.Unlock()
+
+	if dir == "" {
+		return nil, errors.New("diagnostics not enabled")
+	}
+
+	dumpMu.Lock()
+	defer dumpMu.Unlock()
+
+	var (
+		paths []string
+		errs  []error
+		stamp = time.Now().UTC().Format("20060102T150405.000000")
+	)
+	write := func(name string, fn func(f *os.File) error) {
+		path := filepath.Join(dir, stamp+"-"+name)
+		if err := writeFile(path, fn); err != nil {
+			logger().Warn("failed to write diagnostics", "path", path, "err", err)
+			errs = append(errs, err)
+			return
+		}
+		logger().Info("wrote diagnostics", "path", path)
+		paths = append(paths, path)
+	}
+
+	for _, name := range diagnosticsProfiles {
+		p := pprof.Lookup(name)
+		write(name+".pb.gz", func(f *os.File) error { return p.WriteTo(f, 0) })
+	}
+	write("memstats.json", func(f *os.File) error {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		return enc.Encode(&stats)
+	})
+	write("log.txt", func(f *os.File) error {
+		for _, line := range RecentLogLines() {
+			if _, err := fmt.Fprintln(f, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return paths, errors.Join(errs...)
+}
+
+// writeFile creates path and fills it with fn.
+func writeFile(path string, fn func(f *os.File) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(f); err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return f.Close()
+}
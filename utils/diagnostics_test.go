@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpDiagnostics(t *testing.T) {
+	// Created first so that it is removed after the reset has stopped any
+	// dump still in progress.
+	dir := filepath.Join(t.TempDir(), "diag")
+	n := resetSignals(t)
+	WithLogBuffer(16)
+	defer WithLogBuffer(0)
+
+	_, err := DumpDiagnostics()
+	require.Error(t, err)
+
+	require.NoError(t, EnableDiagnosticsSignal(dir))
+	paths, err := DumpDiagnostics()
+	require.NoError(t, err)
+	require.Len(t, paths, 5)
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		require.NoError(t, err)
+		require.NotZero(t, fi.Size(), path)
+	}
+	logs, err := os.ReadFile(paths[4])
+	require.NoError(t, err)
+	require.Contains(t, string(logs), "wrote diagnostics")
+
+	if diagnosticsSignal == nil {
+		return
+	}
+	require.Error(t, SetDiagnosticsSignal(os.Interrupt))
+	n.send(diagnosticsSignal)
+	require.Eventually(t, func() bool {
+		entries, err := os.ReadDir(dir)
+		return err == nil && len(entries) == 10
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// exit and stackDumpOutput are replaceable so tests can observe a forced exit
+// without the test binary dying.
+var (
+	exit                      = os.Exit
+	stackDumpOutput io.Writer = os.Stderr
+)
+
+const (
+	defaultForceExitAfter = 3
+	defaultForceExitCode  = 130
+)
+
+var (
+	forceExitAfter atomic.Int32
+	forceExitCode  atomic.Int32
+)
+
+func init() {
+	forceExitAfter.Store(defaultForceExitAfter)
+	forceExitCode.Store(defaultForceExitCode)
+}
+
+// SetForceExitAfter makes the nth interrupt signal dump all goroutine stacks
+// and exit the process immediately, for when graceful shutdown hangs.  The
+// first signal always starts a graceful shutdown, and shutdown requests never
+// count.  The default is 3; n < 2 disables forced exits.
+func SetForceExitAfter(n int) {
+	forceExitAfter.Store(int32(n))
+}
+
+// SetForceExitCode sets the exit code of a forced exit, 130 by default.
+func SetForceExitCode(code int) {
+	forceExitCode.Store(int32(code))
+}
+
+// forceExitIfNeeded exits the process if count signals reach the threshold
+// set with SetForceExitAfter.
+func forceExitIfNeeded(count int) {
+	n := int(forceExitAfter.Load())
+	if n < 2 || count < n {
+		return
+	}
+	code := int(forceExitCode.Load())
+	logShutdown("forcing exit after repeated signals", "count", count, "code", code)
+	dumpGoroutines(stackDumpOutput)
+	forceExit(code)
+}
+
+// forceExitTimeout bounds how long the OnForceExit callbacks may take in
+// total before the process exits anyway.
+const forceExitTimeout = 100 * time.Millisecond
+
+var (
+	forceExitMu        sync.Mutex
+	forceExitCallbacks []func(code int)
+)
+
+// OnForceExit registers fn to be called with the exit code right before the
+// process is forced to exit, after repeated signals or by the shutdown
+// watchdog, for example to record a metric or write a breadcrumb file.  It is
+// never called on a graceful shutdown.  The callbacks run one after the other
+// and panics are recovered, but the process exits forceExitTimeout (100ms)
+// after the first one started whether they have returned or not, so fn must
+// be very fast.
+func OnForceExit(fn func(code int)) {
+	forceExitMu.Lock()
+	defer forceExitMu.Unlock()
+
+	forceExitCallbacks = append(forceExitCallbacks, fn)
+}
+
+// forceExit runs the OnForceExit callbacks and exits with code.
+func forceExit(code int) {
+	forceExitMu.Lock()
+	fns := append([]func(code int){}, forceExitCallbacks...)
+	forceExitMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, fn := range fns {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						logShutdownAt(logger().Error, "force exit callback panicked", "err", r)
+					}
+				}()
+				fn(code)
+			}()
+		}
+	}()
+	timer := time.NewTimer(forceExitTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		logShutdownAt(logger().Error, "force exit callbacks timed out", "timeout", forceExitTimeout)
+	}
+	exit(code)
+}
+
+// dumpGoroutines writes the stacks of all goroutines to w.
+func dumpGoroutines(w io.Writer) {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			w.Write(buf[:n])
+			return
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
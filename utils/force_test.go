@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestForceExit(t *testing.T) {
+	var (
+		out  bytes.Buffer
+		code = -1
+	)
+	exit, stackDumpOutput = func(c int) { code = c }, &out
+	defer func() {
+		exit, stackDumpOutput = os.Exit, os.Stderr
+		SetForceExitAfter(3)
+		SetForceExitCode(130)
+	}()
+
+	forceExitIfNeeded(2)
+	require.Equal(t, -1, code)
+	forceExitIfNeeded(3)
+	require.Equal(t, 130, code)
+	require.Contains(t, out.String(), "goroutine ")
+
+	SetForceExitAfter(2)
+	SetForceExitCode(42)
+	forceExitIfNeeded(2)
+	require.Equal(t, 42, code)
+
+	code = -1
+	SetForceExitAfter(0)
+	forceExitIfNeeded(10)
+	require.Equal(t, -1, code)
+}
+
+func TestOnForceExit(t *testing.T) {
+	resetSignals(t)
+	var (
+		out    bytes.Buffer
+		code   = -1
+		called []int
+	)
+	exit, stackDumpOutput = func(c int) { code = c }, &out
+	defer func() { exit, stackDumpOutput = os.Exit, os.Stderr }()
+
+	OnForceExit(func(c int) { called = append(called, c) })
+	OnForceExit(func(int) { panic("boom") })
+	OnForceExit(func(c int) { called = append(called, c+1) })
+	forceExitIfNeeded(3)
+	require.Equal(t, 130, code)
+	require.Equal(t, []int{130, 131}, called)
+
+	// A callback that hangs doesn't keep the process alive.
+	OnForceExit(func(int) { select {} })
+	code = -1
+	start := time.Now()
+	forceExitIfNeeded(3)
+	require.Equal(t, 130, code)
+	require.Less(t, time.Since(start), time.Second)
+}
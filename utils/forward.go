@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// ForwardSignalsTo relays the interrupt signals this process receives to the
+// already started cmd, so a detached child shuts down gracefully alongside
+// us.  On Unix the signal goes to the child's process group if it was started
+// with Setpgid, and to the child alone otherwise.  On Windows console signals
+// can't be sent to another process, so forwarding only logs the failure.
+//
+// Waiting for the child remains the caller's job; exited must be closed once
+// cmd.Wait has returned.  Until then the child holds a DefaultTracker token
+// named "child <pid>", so the drain before the shutdown handlers (see
+// SetIdleTimeout) waits for it to exit.  Forwarding ends, and the token is
+// released, once exited is closed or the returned stop function is called.
+// With a nil exited, forwarding only notices the child is gone when the next
+// signal can't be delivered, and no token is held.
+//
+// The signal listener is started if none is running yet, so the signals that
+// are forwarded also trigger shutdown here instead of being swallowed.
+func ForwardSignalsTo(cmd *exec.Cmd, exited <-chan struct{}) (stop func()) {
+	if cmd.Process == nil || signalHandlingDisabled.Load() {
+		return func() {}
+	}
+
+	var tok *Token
+	if exited != nil {
+		// After shutdown has begun the drain is already under way.
+		tok, _ = DefaultTracker.Add(fmt.Sprintf("child %d", cmd.Process.Pid))
+	}
+	startDispatcher()
+	c := make(chan os.Signal, 1)
+	signalsMu.Lock()
+	n := notifier
+	n.Notify(c, interruptSignals...)
+	signalsMu.Unlock()
+
+	quit := make(chan struct{})
+	goWorker(func(reset <-chan struct{}) {
+		defer n.Stop(c)
+		if tok != nil {
+			defer tok.Done()
+		}
+		for {
+			select {
+			case sig := <-c:
+				err := signalProcess(cmd, sig)
+				if errors.Is(err, os.ErrProcessDone) {
+					return
+				}
+				if err != nil {
+					logger().Warn("forward signal failed", "pid", cmd.Process.Pid, "sig", signalName(sig), "err", err)
+				}
+			case <-exited:
+				return
+			case <-quit:
+				return
+			case <-reset:
+				return
+			}
+		}
+	})
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(quit) })
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package utils
+
+import (
+	"os"
+	"os/exec"
+)
+
+func signalProcess(cmd *exec.Cmd, sig os.Signal) error {
+	return cmd.Process.Signal(sig)
+}
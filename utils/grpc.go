@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"net"
+	"time"
+)
+
+// GRPCServer is the part of *grpc.Server used by ServeGRPCUntilInterrupt.  It
+// is an interface so that this package doesn't depend on gRPC.
+type GRPCServer interface {
+	Serve(ln net.Listener) error
+	GracefulStop()
+	Stop()
+}
+
+// ServeGRPCUntilInterrupt serves srv on ln until shutdown is triggered, then
+// stops it gracefully.  If the graceful stop takes longer than grace the
+// server is stopped forcibly (no limit if grace <= 0).  It starts the signal
+// listener if none is running yet.  It returns the error from Serve, which is
+// nil after a stop; an error that makes Serve return before shutdown is
+// returned immediately.
+func ServeGRPCUntilInterrupt(srv GRPCServer, ln net.Listener, grace time.Duration) error {
+	startDispatcher()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-shutdownDone():
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	var timeout <-chan time.Time
+	if grace > 0 {
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-stopped:
+	case <-timeout:
+		logShutdown("grpc graceful stop timed out", "grace", grace)
+		srv.Stop()
+	}
+	return <-serveErr
+}
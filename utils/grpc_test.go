@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGRPCServer serves until stopped.  GracefulStop returns once release is
+// closed, or straight away if release is nil.
+type fakeGRPCServer struct {
+	serveErr error
+	release  chan struct{}
+	stopped  chan struct{}
+	forced   atomic.Bool
+}
+
+func newFakeGRPCServer() *fakeGRPCServer {
+	return &fakeGRPCServer{stopped: make(chan struct{})}
+}
+
+func (s *fakeGRPCServer) Serve(net.Listener) error {
+	if s.serveErr != nil {
+		return s.serveErr
+	}
+	<-s.stopped
+	return nil
+}
+
+func (s *fakeGRPCServer) GracefulStop() {
+	if s.release != nil {
+		<-s.release
+	}
+	s.stop()
+}
+
+func (s *fakeGRPCServer) Stop() {
+	s.forced.Store(true)
+	s.stop()
+}
+
+func (s *fakeGRPCServer) stop() {
+	select {
+	case <-s.stopped:
+	default:
+		close(s.stopped)
+	}
+}
+
+func TestServeGRPCUntilInterrupt(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		grace  time.Duration
+		stuck  bool
+		forced bool
+	}{
+		{"graceful", time.Second, false, false},
+		{"grace exceeded", 50 * time.Millisecond, true, true},
+		{"no grace limit", 0, true, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			n := resetSignals(t)
+			srv := newFakeGRPCServer()
+			if tc.stuck {
+				srv.release = make(chan struct{})
+			}
+
+			served := make(chan error, 1)
+			go func() { served <- ServeGRPCUntilInterrupt(srv, nil, tc.grace) }()
+			waitListening(t)
+			n.send(interruptSignals[0])
+
+			if tc.stuck && !tc.forced {
+				select {
+				case <-served:
+					t.Fatal("returned before the graceful stop finished")
+				case <-time.After(100 * time.Millisecond):
+				}
+				close(srv.release)
+			}
+			select {
+			case err := <-served:
+				require.NoError(t, err)
+			case <-time.After(time.Second):
+				t.Fatal("server was not stopped")
+			}
+			require.Equal(t, tc.forced, srv.forced.Load())
+			if tc.stuck && tc.forced {
+				close(srv.release)
+			}
+		})
+	}
+}
+
+func TestServeGRPCUntilInterruptServeError(t *testing.T) {
+	resetSignals(t)
+	srv := newFakeGRPCServer()
+	srv.serveErr = errors.New("listener closed")
+
+	require.ErrorIs(t, ServeGRPCUntilInterrupt(srv, nil, time.Second), srv.serveErr)
+	require.False(t, InterruptRequested(NewDoneChannel()))
+}
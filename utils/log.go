@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Logger is the structured logger used by the signal and shutdown helpers.
+// Both go-ethereum's log.Logger and the standard library's *slog.Logger
+// satisfy it, so embedders can plug in either.
+type Logger interface {
+	Debug(msg string, ctx ...interface{})
+	Info(msg string, ctx ...interface{})
+	Warn(msg string, ctx ...interface{})
+	Error(msg string, ctx ...interface{})
+}
+
+type loggerHolder struct {
+	Logger
+	buf *logBuffer
+}
+
+var currentLogger atomic.Value
+
+func init() {
+	currentLogger.Store(loggerHolder{Logger: gethLogger{}})
+}
+
+// SetLogger replaces the logger used by this package.  Passing nil restores
+// the default, which forwards to go-ethereum's root logger.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = gethLogger{}
+	}
+	h := currentLogger.Load().(loggerHolder)
+	currentLogger.Store(loggerHolder{Logger: l, buf: h.buf})
+}
+
+// WithLogBuffer keeps the last n lines logged by this package in memory, in
+// addition to passing them on to the logger, so that they can be inspected
+// with RecentLogLines after something went wrong.  n <= 0 turns it off.
+func WithLogBuffer(n int) {
+	h := currentLogger.Load().(loggerHolder)
+	h.buf = nil
+	if n > 0 {
+		h.buf = &logBuffer{lines: make([]atomic.Pointer[string], n)}
+	}
+	currentLogger.Store(h)
+}
+
+// RecentLogLines returns the lines kept by WithLogBuffer, oldest first.
+func RecentLogLines() []string {
+	if buf := currentLogger.Load().(loggerHolder).buf; buf != nil {
+		return buf.snapshot()
+	}
+	return nil
+}
+
+func logger() Logger {
+	h := currentLogger.Load().(loggerHolder)
+	if h.buf != nil {
+		return bufferedLogger(h)
+	}
+	return h.Logger
+}
+
+// gethLogger forwards to go-ethereum's root logger, resolving it on every call
+// so that a later log.SetDefault is picked up.
+type gethLogger struct{}
+
+func (gethLogger) Debug(msg string, ctx ...interface{}) { log.Debug(msg, ctx...) }
+func (gethLogger) Info(msg string, ctx ...interface{})  { log.Info(msg, ctx...) }
+func (gethLogger) Warn(msg string, ctx ...interface{})  { log.Warn(msg, ctx...) }
+func (gethLogger) Error(msg string, ctx ...interface{}) { log.Error(msg, ctx...) }
+
+// logBuffer is a fixed-size ring of log lines.  Writers only contend on an
+// atomic counter, so it is cheap to keep enabled.
+type logBuffer struct {
+	next  atomic.Uint64
+	lines []atomic.Pointer[string]
+}
+
+func (b *logBuffer) add(level, msg string, ctx []interface{}) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %-5s %s", time.Now().Format(time.RFC3339Nano), level, msg)
+	for i := 0; i+1 < len(ctx); i += 2 {
+		fmt.Fprintf(&sb, " %v=%v", ctx[i], ctx[i+1])
+	}
+	line := sb.String()
+
+	i := b.next.Add(1) - 1
+	b.lines[i%uint64(len(b.lines))].Store(&line)
+}
+
+func (b *logBuffer) snapshot() []string {
+	n := uint64(len(b.lines))
+	end := b.next.Load()
+	start := uint64(0)
+	if end > n {
+		start = end - n
+	}
+	out := make([]string, 0, end-start)
+	for i := start; i < end; i++ {
+		if line := b.lines[i%n].Load(); line != nil {
+			out = append(out, *line)
+		}
+	}
+	return out
+}
+
+type bufferedLogger loggerHolder
+
+func (l bufferedLogger) Debug(msg string, ctx ...interface{}) {
+	l.buf.add("DEBUG", msg, ctx)
+	l.Logger.Debug(msg, ctx...)
+}
+
+func (l bufferedLogger) Info(msg string, ctx ...interface{}) {
+	l.buf.add("INFO", msg, ctx)
+	l.Logger.Info(msg, ctx...)
+}
+
+func (l bufferedLogger) Warn(msg string, ctx ...interface{}) {
+	l.buf.add("WARN", msg, ctx)
+	l.Logger.Warn(msg, ctx...)
+}
+
+func (l bufferedLogger) Error(msg string, ctx ...interface{}) {
+	l.buf.add("ERROR", msg, ctx)
+	l.Logger.Error(msg, ctx...)
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogBuffer(t *testing.T) {
+	WithLogBuffer(2)
+	defer WithLogBuffer(0)
+
+	logger().Info("first")
+	logger().Warn("second", "k", 1)
+	logger().Error("third")
+
+	lines := RecentLogLines()
+	require.Len(t, lines, 2)
+	require.Contains(t, lines[0], "WARN  second k=1")
+	require.Contains(t, lines[1], "ERROR third")
+}
+
+func TestShutdownLogFields(t *testing.T) {
+	resetSignals(t)
+	WithLogBuffer(16)
+	defer WithLogBuffer(0)
+
+	runShutdownHandlers([]shutdownHandler{
+		{name: "ok", fn: func(context.Context) error { return nil }},
+		{name: "failing", fn: func(context.Context) error { return errors.New("boom") }},
+	})
+	tok, err := DefaultTracker.Add("batch")
+	require.NoError(t, err)
+	defer tok.Done()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Error(t, DefaultTracker.AwaitIdle(ctx))
+
+	lines := RecentLogLines()
+	require.Len(t, lines, 3)
+	require.Contains(t, lines[0], "INFO  shutdown handler done component=shutdown phase=handlers handler=ok duration=")
+	require.Contains(t, lines[1], "ERROR shutdown handler failed component=shutdown phase=handlers handler=failing duration=")
+	require.Contains(t, lines[1], "err=boom")
+	require.Contains(t, lines[2], "WARN  work still in flight component=shutdown phase=drain work=batch age=")
+}
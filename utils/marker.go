@@ -0,0 +1,218 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrAlreadyRunning is returned when the lock file of a marker is held by
+// another process, which is therefore still running.
+var ErrAlreadyRunning = errors.New("another instance is running")
+
+// PrevRunInfo is the content of the marker file written by MarkStarted and
+// MarkCleanShutdown.  Its JSON encoding is stable so that scripts can parse
+// it; Stopped is the zero time unless Clean is set.  PID is informational:
+// it may have been reused by an unrelated process since.
+type PrevRunInfo struct {
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+	Stopped time.Time `json:"stopped"`
+	Clean   bool      `json:"clean"`
+}
+
+// MarkStarted records in the marker file at path that this process is
+// running, so that the next run can tell with WasCleanShutdown whether this
+// one ended cleanly.  It fails with ErrAlreadyRunning if another process
+// holds the lock file next to the marker (path with ".lock" appended), which
+// is held until MarkCleanShutdown or the process exits; a marker left behind
+// by a crashed process is replaced.  The marker is rewritten by
+// MarkCleanShutdown, which runs automatically after the other shutdown
+// handlers.  The lock file itself is left in place.
+func MarkStarted(path string) error {
+	if err := lockMarker(path); err != nil {
+		return err
+	}
+	info := PrevRunInfo{PID: os.Getpid(), Started: time.Now().UTC()}
+	if err := writeMarker(path, info); err != nil {
+		unlockMarker(path)
+		return err
+	}
+	logger().Info("marked started", "path", path, "pid", info.PID)
+
+	// Marking the same path again keeps the handler registered the first
+	// time.
+	err := RegisterShutdownHandler("clean-shutdown-marker "+path, math.MinInt, 0, func(ctx context.Context) error {
+		return MarkCleanShutdown(path)
+	})
+	if errors.Is(err, ErrDuplicateHandler) {
+		return nil
+	}
+	return err
+}
+
+// MarkCleanShutdown records in the marker file at path that this process
+// shut down cleanly.
+func MarkCleanShutdown(path string) error {
+	info, err := readMarker(path)
+	if err != nil {
+		return err
+	}
+	if info.PID != os.Getpid() {
+		return fmt.Errorf("%s belongs to pid %d", path, info.PID)
+	}
+	info.Stopped, info.Clean = time.Now().UTC(), true
+	if err := writeMarker(path, info); err != nil {
+		return err
+	}
+	unlockMarker(path)
+	logger().Info("marked clean shutdown", "path", path)
+	return nil
+}
+
+// WasCleanShutdown reports whether the previous run that used the marker file
+// at path ended cleanly, and what it recorded.  It is meant to be called at
+// startup, before MarkStarted.  Without a marker there was no previous run,
+// which counts as clean.  If another process holds the marker's lock file the
+// error is ErrAlreadyRunning.
+func WasCleanShutdown(path string) (bool, PrevRunInfo, error) {
+	info, err := readMarker(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return true, PrevRunInfo{}, nil
+	}
+	if err != nil {
+		return false, PrevRunInfo{}, err
+	}
+	locked, err := markerLocked(path)
+	if err != nil {
+		return false, info, err
+	}
+	if locked {
+		return false, info, fmt.Errorf("%w: %s is locked, pid %d", ErrAlreadyRunning, path, info.PID)
+	}
+	return info.Clean, info, nil
+}
+
+func readMarker(path string) (PrevRunInfo, error) {
+	var info PrevRunInfo
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return info, err
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, fmt.Errorf("invalid marker %s: %w", path, err)
+	}
+	return info, nil
+}
+
+// writeMarker writes info to path through a temporary file, so the marker is
+// never seen half written.
+func writeMarker(path string, info PrevRunInfo) error {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// markerLocks holds the open lock file of every marker locked by MarkStarted,
+// by absolute marker path.  The operating system releases the lock when the
+// file is closed or the process exits.
+var (
+	markerLocksMu sync.Mutex
+	markerLocks   = make(map[string]*os.File)
+)
+
+// errLocked is returned by lockFile if another process holds the lock.
+var errLocked = errors.New("locked")
+
+// lockMarker takes the lock file of the marker at path unless this process
+// already holds it.
+func lockMarker(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	markerLocksMu.Lock()
+	defer markerLocksMu.Unlock()
+
+	if markerLocks[abs] != nil {
+		return nil
+	}
+	f, err := os.OpenFile(abs+".lock", os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		if errors.Is(err, errLocked) {
+			return fmt.Errorf("%w: %s is locked", ErrAlreadyRunning, path)
+		}
+		return err
+	}
+	markerLocks[abs] = f
+	return nil
+}
+
+// markerLocked reports whether another process holds the lock file of the
+// marker at path.
+func markerLocked(path string) (bool, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false, err
+	}
+	markerLocksMu.Lock()
+	defer markerLocksMu.Unlock()
+
+	if markerLocks[abs] != nil {
+		return false, nil
+	}
+	f, err := os.OpenFile(abs+".lock", os.O_RDWR, 0)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	err = lockFile(f)
+	if errors.Is(err, errLocked) {
+		return true, nil
+	}
+	return false, err
+}
+
+// unlockMarker releases the lock file of the marker at path, if held.
+func unlockMarker(path string) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	markerLocksMu.Lock()
+	defer markerLocksMu.Unlock()
+
+	if f := markerLocks[abs]; f != nil {
+		f.Close()
+		delete(markerLocks, abs)
+	}
+}
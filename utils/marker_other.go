@@ -0,0 +1,11 @@
+//go:build !unix && !windows
+
+package utils
+
+import "os"
+
+// lockFile does nothing on platforms such as js/wasm that have no file locks,
+// so there MarkStarted can't tell that another instance is running.
+func lockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestMarker(t *testing.T, path string, info PrevRunInfo) {
+	data, err := json.Marshal(info)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+}
+
+func TestMarkerCleanRun(t *testing.T) {
+	resetSignals(t)
+	path := filepath.Join(t.TempDir(), "run.marker")
+
+	clean, info, err := WasCleanShutdown(path)
+	require.NoError(t, err)
+	require.True(t, clean)
+	require.Zero(t, info)
+
+	require.NoError(t, MarkStarted(path))
+	clean, info, err = WasCleanShutdown(path)
+	require.NoError(t, err)
+	require.False(t, clean)
+	require.Equal(t, os.Getpid(), info.PID)
+
+	// The marker is rewritten after the shutdown handlers have run.
+	RequestShutdown("test")
+	WaitForShutdown()
+	clean, info, err = WasCleanShutdown(path)
+	require.NoError(t, err)
+	require.True(t, clean)
+	require.Equal(t, os.Getpid(), info.PID)
+	require.False(t, info.Stopped.Before(info.Started))
+}
+
+func TestMarkerCrash(t *testing.T) {
+	resetSignals(t)
+	path := filepath.Join(t.TempDir(), "run.marker")
+
+	// A process that has exited stands in for one that crashed.
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	require.NoError(t, cmd.Run())
+	started := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	writeTestMarker(t, path, PrevRunInfo{PID: cmd.Process.Pid, Started: started})
+
+	clean, info, err := WasCleanShutdown(path)
+	require.NoError(t, err)
+	require.False(t, clean)
+	require.Equal(t, cmd.Process.Pid, info.PID)
+	require.True(t, info.Started.Equal(started))
+	require.True(t, info.Stopped.IsZero())
+
+	// The stale marker doesn't stop the next run.
+	require.NoError(t, MarkStarted(path))
+	_, info, err = WasCleanShutdown(path)
+	require.NoError(t, err)
+	require.Equal(t, os.Getpid(), info.PID)
+}
+
+func TestMarkerReusedPID(t *testing.T) {
+	resetSignals(t)
+	path := filepath.Join(t.TempDir(), "run.marker")
+
+	// The crashed run's pid now belongs to a running process, the parent
+	// (go test), but nothing holds the lock.
+	writeTestMarker(t, path, PrevRunInfo{PID: os.Getppid(), Started: time.Now().UTC()})
+
+	clean, info, err := WasCleanShutdown(path)
+	require.NoError(t, err)
+	require.False(t, clean)
+	require.Equal(t, os.Getppid(), info.PID)
+	require.NoError(t, MarkStarted(path))
+}
+
+func TestMarkerLocked(t *testing.T) {
+	resetSignals(t)
+	path := filepath.Join(t.TempDir(), "run.marker")
+
+	// After a clean previous run, another instance holding the lock has not
+	// written its marker yet.
+	writeTestMarker(t, path, PrevRunInfo{PID: os.Getppid(), Clean: true})
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o644)
+	require.NoError(t, err)
+	require.NoError(t, lockFile(f))
+	require.ErrorIs(t, MarkStarted(path), ErrAlreadyRunning)
+	_, info, err := WasCleanShutdown(path)
+	require.ErrorIs(t, err, ErrAlreadyRunning)
+	require.Equal(t, os.Getppid(), info.PID, "marker was rewritten without the lock")
+	require.Error(t, MarkCleanShutdown(path))
+
+	require.NoError(t, f.Close())
+	require.NoError(t, MarkStarted(path))
+	require.NoError(t, MarkStarted(path), "this process already holds the lock")
+	f, err = os.OpenFile(path+".lock", os.O_RDWR, 0)
+	require.NoError(t, err)
+	defer f.Close()
+	require.ErrorIs(t, lockFile(f), errLocked)
+
+	// A clean shutdown releases the lock.
+	require.NoError(t, MarkCleanShutdown(path))
+	require.NoError(t, lockFile(f))
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// ReasonParentExit is the shutdown reason logged when the parent process goes
+// away.
+const ReasonParentExit = "parent-exit"
+
+// ShutdownOnParentExit triggers a graceful shutdown when the process that
+// launched us exits, instead of lingering after being reparented to init.
+//
+// If pipe is non-nil it is read until EOF or an error, which happens once the
+// parent closes its end of a control pipe (or of our stdin); this works on
+// every platform.  If pipe is nil the Linux-only PR_SET_PDEATHSIG path is used:
+// the kernel sends SIGTERM when the parent dies, the shared signal dispatcher
+// catches it, and a changed parent PID tells it apart from any other SIGTERM.
+// SIGTERM must therefore stay among the interrupt signals.  On other platforms
+// a nil pipe returns an error.
+func ShutdownOnParentExit(pipe io.Reader) error {
+	if pipe == nil {
+		return watchParentDeath()
+	}
+
+	go func() {
+		io.Copy(io.Discard, pipe)
+		parentExited()
+	}()
+	return nil
+}
+
+func parentExited() {
+	requestShutdown(ReasonParentExit, "ppid", os.Getppid())
+}
+
+// watchedParent is the parent PID recorded by the PR_SET_PDEATHSIG path, or 0
+// when the parent isn't watched that way.  getppid is replaced by tests.
+var (
+	watchedParent atomic.Int64
+	getppid       = os.Getppid
+)
+
+// parentDied reports whether sig is the death signal of a watched parent.
+func parentDied(sig os.Signal) bool {
+	ppid := watchedParent.Load()
+	return ppid != 0 && sig == parentDeathSignal && int64(getppid()) != ppid
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"errors"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const parentDeathSignal = syscall.SIGTERM
+
+func watchParentDeath() error {
+	if signalHandlingDisabled.Load() {
+		return errors.New("parent death signal requires signal handling")
+	}
+
+	// The dispatcher must be catching SIGTERM before the kernel may send it.
+	startDispatcher()
+	ppid := getppid()
+	watchedParent.Store(int64(ppid))
+	if err := unix.Prctl(unix.PR_SET_PDEATHSIG, uintptr(parentDeathSignal), 0, 0, 0); err != nil {
+		watchedParent.Store(0)
+		return err
+	}
+
+	// The parent may already be gone by the time the death signal was armed,
+	// in which case it is never sent.
+	if getppid() != ppid {
+		parentExited()
+	}
+	return nil
+}
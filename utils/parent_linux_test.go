@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestShutdownOnParentExitDeathSignal(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		parentDied bool
+		reason     string
+	}{
+		{"parent exited", true, ReasonParentExit},
+		{"plain SIGTERM", false, "signal"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(func() {
+				getppid = os.Getppid
+				unix.Prctl(unix.PR_SET_PDEATHSIG, 0, 0, 0, 0)
+			})
+			n := resetSignals(t)
+			WithLogBuffer(16)
+			defer WithLogBuffer(0)
+
+			ppid := syscall.Getppid()
+			getppid = func() int { return ppid }
+			require.NoError(t, ShutdownOnParentExit(nil))
+			waitListening(t)
+			notifies, _ := n.counts()
+			require.Equal(t, 1, notifies, "the death signal has no subscription of its own")
+
+			if tc.parentDied {
+				getppid = func() int { return 1 }
+			}
+			n.send(syscall.SIGTERM)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_, err := WaitForShutdownCtx(ctx)
+			require.NoError(t, err)
+			_, reason, _ := shutdownState()
+			require.Equal(t, tc.reason, reason)
+			require.Equal(t, int64(1), InterruptStats().Signals["SIGTERM"])
+
+			logs := strings.Join(RecentLogLines(), "\n")
+			require.Equal(t, 1, strings.Count(logs, "reason="+tc.reason), logs)
+		})
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package utils
+
+import (
+	"errors"
+	"syscall"
+)
+
+const parentDeathSignal = syscall.SIGTERM
+
+func watchParentDeath() error {
+	return errors.New("parent death signal is only supported on Linux, pass a pipe instead")
+}
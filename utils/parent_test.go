@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdownOnParentExitPipe(t *testing.T) {
+	resetSignals(t)
+
+	r, w := io.Pipe()
+	require.NoError(t, ShutdownOnParentExit(r))
+	require.False(t, InterruptRequested(NewDoneChannel()))
+
+	w.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := WaitForShutdownCtx(ctx)
+	require.NoError(t, err)
+	_, reason, _ := shutdownState()
+	require.Equal(t, ReasonParentExit, reason)
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// PauseEvent is sent on PauseFeed whenever the process is paused or resumed.
+type PauseEvent struct {
+	Paused bool
+	Signal os.Signal // nil for RequestPause and RequestResume
+	Time   time.Time
+}
+
+var (
+	// PauseFeed receives a PauseEvent whenever the pause state changes,
+	// through a pause signal (SIGUSR1 on Unix), a resume signal (SIGUSR2)
+	// or RequestPause and RequestResume.  Events are delivered once a
+	// listener has been started.  Pausing has no effect on shutdown, and
+	// the state no longer changes once shutdown has begun.
+	PauseFeed = event.Feed{}
+
+	pauseMu     sync.Mutex
+	paused      atomic.Bool
+	pauseEvents = make(chan PauseEvent, 16)
+)
+
+// RequestPause pauses the process the same way as the pause signal does.
+// Pausing while already paused is logged and otherwise ignored.
+func RequestPause() {
+	setPaused(true, nil)
+}
+
+// RequestResume resumes the process the same way as the resume signal does.
+// Resuming while not paused is logged and otherwise ignored.
+func RequestResume() {
+	setPaused(false, nil)
+}
+
+// IsPaused reports whether the process is paused.  It is cheap enough to be
+// polled from worker loops.
+func IsPaused() bool {
+	return paused.Load()
+}
+
+// SubscribePauseResume subscribes to PauseFeed with a channel of the given
+// buffer size.
+func SubscribePauseResume(buffer int) (<-chan PauseEvent, event.Subscription) {
+	c := make(chan PauseEvent, buffer)
+	return c, PauseFeed.Subscribe(c)
+}
+
+// setPaused changes the pause state and queues a PauseEvent for it.  sig is
+// the signal that asked for it, or nil for a request.
+func setPaused(p bool, sig os.Signal) {
+	msg, ctx := "resumed", []interface{}{"reason", "request"}
+	if p {
+		msg = "paused"
+	}
+	if sig != nil {
+		ctx = []interface{}{"reason", "signal", "sig", signalName(sig)}
+	}
+
+	if InterruptRequested(shutdownDone()) {
+		logger().Info("ignored pause state change during shutdown", append(ctx, "paused", p)...)
+		return
+	}
+
+	pauseMu.Lock()
+	defer pauseMu.Unlock()
+
+	if paused.Load() == p {
+		logger().Info("already "+msg, ctx...)
+		return
+	}
+	paused.Store(p)
+	select {
+	case pauseEvents <- PauseEvent{Paused: p, Signal: sig, Time: time.Now()}:
+	default:
+		logger().Warn("dropped pause event, feed is backed up", "paused", p)
+	}
+	logger().Info(msg, ctx...)
+}
+
+func deliverPauses(quit <-chan struct{}) {
+	for {
+		select {
+		case ev := <-pauseEvents:
+			PauseFeed.Send(ev)
+		case <-quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauseResume(t *testing.T) {
+	tests := []struct {
+		name          string
+		pause, resume func()
+		sigs          []os.Signal // expected PauseEvent.Signal for pause and resume
+	}{
+		{"request", RequestPause, RequestResume, []os.Signal{nil, nil}},
+		{"signal", func() { SimulateInterrupt(pauseSignal) }, func() { SimulateInterrupt(resumeSignal) }, []os.Signal{pauseSignal, resumeSignal}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.name == "signal" && pauseSignal == nil {
+				t.Skip("no pause signal on this platform")
+			}
+			resetSignals(t)
+			WithLogBuffer(16)
+			defer WithLogBuffer(0)
+
+			events, sub := SubscribePauseResume(4)
+			defer sub.Unsubscribe()
+			interrupted := InterruptListener()
+
+			tt.pause()
+			tt.pause()
+			tt.resume()
+			tt.pause()
+			SimulateInterrupt(os.Interrupt)
+			select {
+			case <-interrupted:
+			case <-time.After(time.Second):
+				t.Fatal("interrupt while paused did not shut down")
+			}
+			tt.resume()
+
+			for i, want := range []bool{true, false, true} {
+				select {
+				case ev := <-events:
+					require.Equal(t, want, ev.Paused)
+					require.Equal(t, tt.sigs[i%2], ev.Signal)
+				case <-time.After(time.Second):
+					t.Fatalf("no pause event with Paused=%v", want)
+				}
+			}
+			select {
+			case ev := <-events:
+				t.Fatalf("unexpected pause event %+v", ev)
+			case <-time.After(50 * time.Millisecond):
+			}
+			require.True(t, IsPaused())
+			require.Contains(t, strings.Join(RecentLogLines(), "\n"), "already paused")
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Readiness state.  readyChannel is closed while the process is ready and
+// replaced by a fresh one whenever it becomes not ready again.
+var (
+	readyMu      sync.Mutex
+	ready        bool
+	readyReason  = "starting"
+	readyChannel = make(chan struct{})
+)
+
+// MarkReady records that the process is ready to serve and releases everyone
+// blocked in WaitForReady.  Only an actual transition is logged, so calling it
+// repeatedly is harmless.
+func MarkReady() {
+	readyMu.Lock()
+	defer readyMu.Unlock()
+
+	if ready {
+		return
+	}
+	ready = true
+	readyReason = ""
+	close(readyChannel)
+	if !shutdownBegun.Load() {
+		updateReadyGauge(1)
+	}
+	logger().Info("marked ready")
+}
+
+// MarkNotReady records that the process temporarily can't serve, for example
+// while reconnecting to a backend.  Only an actual transition is logged.
+func MarkNotReady(reason string) {
+	readyMu.Lock()
+	defer readyMu.Unlock()
+
+	if !ready {
+		return
+	}
+	ready = false
+	readyReason = reason
+	readyChannel = make(chan struct{})
+	updateReadyGauge(0)
+	logger().Info("marked not ready", "reason", reason)
+}
+
+// IsReady reports whether the process is ready: MarkReady was called more
+// recently than MarkNotReady and shutdown hasn't begun.  The second result is
+// the reason given to MarkNotReady, or "shutdown".
+func IsReady() (bool, string) {
+	if InterruptRequested(shutdownDone()) {
+		return false, "shutdown"
+	}
+
+	readyMu.Lock()
+	defer readyMu.Unlock()
+
+	return ready, readyReason
+}
+
+// shutdownNotReady reports the process as not ready in the metrics once
+// shutdown has begun, as IsReady does.
+func shutdownNotReady() {
+	readyMu.Lock()
+	defer readyMu.Unlock()
+
+	updateReadyGauge(0)
+}
+
+func updateReadyGauge(v int64) {
+	metrics.GetOrRegisterGauge(readyMetric, nil).Update(v)
+}
+
+// WaitForReady blocks until the process is ready, returning nil, or until ctx
+// is done, returning ctx.Err().
+func WaitForReady(ctx context.Context) error {
+	readyMu.Lock()
+	c := readyChannel
+	readyMu.Unlock()
+
+	select {
+	case <-c:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ReadinessHandler answers 200 while IsReady reports true and 503 with the
+// reason otherwise.
+func ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, reason := IsReady(); !ok {
+			http.Error(w, "not ready: "+reason, http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok\n"))
+	})
+}
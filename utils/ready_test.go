@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadinessTransitions(t *testing.T) {
+	resetSignals(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, WaitForReady(ctx), context.DeadlineExceeded)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() { defer wg.Done(); MarkReady() }()
+		go func() { defer wg.Done(); MarkNotReady("reconnecting") }()
+	}
+	wg.Wait()
+
+	MarkReady()
+	require.NoError(t, WaitForReady(context.Background()))
+	MarkNotReady("reconnecting")
+	MarkNotReady("again")
+
+	readyMu.Lock()
+	require.False(t, ready)
+	require.Equal(t, "reconnecting", readyReason)
+	readyMu.Unlock()
+	MarkReady()
+}
+
+func TestReadinessDuringShutdown(t *testing.T) {
+	// Other tests may have registered a no-op gauge while metrics were
+	// disabled.
+	metrics.Unregister(readyMetric)
+	metrics.Enabled = true
+	t.Cleanup(func() {
+		metrics.Enabled = false
+		metrics.Unregister(readyMetric)
+	})
+	resetSignals(t)
+	gauge := func() int64 { return metrics.GetOrRegisterGauge(readyMetric, nil).Snapshot().Value() }
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		ReadinessHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return w
+	}
+
+	MarkReady()
+	require.Equal(t, http.StatusOK, get().Code)
+	require.EqualValues(t, 1, gauge())
+
+	RequestShutdown("test")
+	w := get()
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.Contains(t, w.Body.String(), "not ready: shutdown")
+	require.EqualValues(t, 0, gauge())
+
+	// Becoming ready again during shutdown doesn't report ready.
+	MarkNotReady("reconnecting")
+	MarkReady()
+	require.Equal(t, http.StatusServiceUnavailable, get().Code)
+	require.EqualValues(t, 0, gauge())
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"os"
+
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// ReloadFeed receives a struct{}{} whenever a reload signal (SIGHUP on Unix)
+// arrives before shutdown has begun, so that log files can be rotated and
+// configuration reloaded without a restart.  Reload signals are only handled
+// once a listener has been started.  On Windows there is no reload signal and
+// the feed never fires.
+var ReloadFeed = event.Feed{}
+
+// reloadRequests decouples ReloadFeed subscribers from the dispatcher, so a
+// slow reload never delays interrupt handling.  A reload that arrives while
+// one is still pending is folded into it.
+var reloadRequests = make(chan struct{}, 1)
+
+func requestReload(sig os.Signal) {
+	select {
+	case reloadRequests <- struct{}{}:
+		logger().Info("received reload signal", "sig", signalName(sig))
+	default:
+		logger().Info("received reload signal, reload already pending", "sig", signalName(sig))
+	}
+}
+
+func deliverReloads(quit <-chan struct{}) {
+	for {
+		select {
+		case <-reloadRequests:
+			ReloadFeed.Send(struct{}{})
+		case <-quit:
+			return
+		}
+	}
+}
+
+// OnReload calls fn on its own goroutine for every reload event until the
+// returned subscription is unsubscribed.
+func OnReload(fn func()) event.Subscription {
+	c := make(chan struct{}, 1)
+	sub := ReloadFeed.Subscribe(c)
+	go func() {
+		for {
+			select {
+			case <-c:
+				fn()
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+	return sub
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"runtime/debug"
+	"syscall"
+)
+
+// ReasonRunExit is the shutdown reason logged when the function given to Run
+// returns before anything else triggered shutdown.
+const ReasonRunExit = "run-exit"
+
+// panicExitCode is the exit code Run returns when fn panics, the same as for
+// an unrecovered panic.
+const panicExitCode = 2
+
+// Run runs fn and turns its outcome into a conventional exit code, so that
+// main reduces to os.Exit(utils.Run(realMain)).  fn is given a channel that
+// is closed when shutdown begins and should return soon after; use
+// ArmShutdownWatchdog to bound how long that may take.  Once fn has returned,
+// shutdown is requested unless it has begun already, and Run waits for the
+// registered shutdown handlers before it returns.
+//
+// The exit code is 2 if fn panicked, 128 plus the signal number if a signal
+// triggered shutdown (130 for SIGINT, 143 for SIGTERM), 1 if fn returned an
+// error and 0 otherwise.  Panics and errors are logged, a panic with its
+// stack.
+func Run(fn func(shutdown <-chan struct{}) error) int {
+	shutdown := InterruptListener()
+	defer ReleaseDoneChannel(shutdown)
+
+	err, panicked := runRecovered(fn, shutdown)
+	if err != nil {
+		logger().Error("run failed", "err", err)
+	}
+	if !InterruptRequested(shutdown) {
+		requestShutdown(ReasonRunExit)
+	}
+	sig := WaitForShutdown()
+
+	switch s, ok := sig.(syscall.Signal); {
+	case panicked:
+		return panicExitCode
+	case ok:
+		return 128 + int(s)
+	case err != nil:
+		return 1
+	}
+	return 0
+}
+
+// runRecovered calls fn, turning a panic into a log line.
+func runRecovered(fn func(shutdown <-chan struct{}) error, shutdown <-chan struct{}) (err error, panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger().Error("run panicked", "panic", r, "stack", string(debug.Stack()))
+			panicked = true
+		}
+	}()
+	return fn(shutdown), false
+}
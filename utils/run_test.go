@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	interruptWith := func(sig os.Signal) func(<-chan struct{}) error {
+		return func(shutdown <-chan struct{}) error {
+			go SimulateInterrupt(sig)
+			<-shutdown
+			return context.Canceled
+		}
+	}
+	tests := []struct {
+		name string
+		fn   func(shutdown <-chan struct{}) error
+		code int
+		log  string
+	}{
+		{"clean", func(<-chan struct{}) error { return nil }, 0, ""},
+		{"error", func(<-chan struct{}) error { return errors.New("boom") }, 1, "run failed"},
+		{"SIGINT", interruptWith(os.Interrupt), 130, ""},
+		{"SIGTERM", interruptWith(syscall.SIGTERM), 143, ""},
+		{"panic", func(<-chan struct{}) error { panic("boom") }, panicExitCode, "run panicked"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetSignals(t)
+			WithLogBuffer(16)
+			defer WithLogBuffer(0)
+
+			handled := false
+			require.NoError(t, RegisterShutdownHandler("test", 0, 0, func(context.Context) error {
+				handled = true
+				return nil
+			}))
+
+			require.Equal(t, tt.code, Run(tt.fn))
+			require.True(t, handled)
+			if tt.log != "" {
+				logs := strings.Join(RecentLogLines(), "\n")
+				require.Contains(t, logs, tt.log)
+				require.Contains(t, logs, "boom")
+			}
+		})
+	}
+}
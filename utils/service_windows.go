@@ -0,0 +1,36 @@
+//go:build windows
+
+package utils
+
+import (
+	"golang.org/x/sys/windows/svc"
+)
+
+// HandleServiceControl bridges the Windows Service Control Manager to the
+// interrupt machinery for programs run as a service, where stop requests do
+// not arrive as console signals.  It is meant to be called from a
+// svc.Handler's Execute method: it reports Running, maps Stop and Shutdown
+// requests to the same shutdown trigger as an interrupt signal, reports
+// StopPending and returns once shutdown has begun.  Execute should then run
+// its cleanup and return, at which point the service is reported Stopped.
+func HandleServiceControl(req <-chan svc.ChangeRequest, status chan<- svc.Status) {
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	done := shutdownDone()
+	for {
+		select {
+		case c := <-req:
+			switch c.Cmd {
+			case svc.Interrogate:
+				status <- c.CurrentStatus
+
+			case svc.Stop, svc.Shutdown:
+				requestShutdown("service", "cmd", c.Cmd)
+			}
+
+		case <-done:
+			status <- svc.Status{State: svc.StopPending}
+			return
+		}
+	}
+}
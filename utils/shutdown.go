@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrShutdownInProgress is returned when registering a shutdown handler or
+// tracking work after shutdown has begun.
+var ErrShutdownInProgress = errors.New("shutdown in progress")
+
+// ErrDuplicateHandler is returned when registering a shutdown handler under a
+// name that is already taken, unless SetDuplicateHandlerSuffix is on.
+var ErrDuplicateHandler = errors.New("duplicate shutdown handler name")
+
+type shutdownHandler struct {
+	name     string
+	priority int
+	timeout  time.Duration
+	fn       func(ctx context.Context) error
+}
+
+// handlersDone is closed once the handlers have run.  It is guarded by
+// handlersMu along with the rest, since ResetForTesting replaces it.
+var (
+	handlersMu      sync.Mutex
+	handlers        []shutdownHandler
+	handlersStarted bool
+	handlersDone    = make(chan struct{})
+
+	suffixDuplicateHandlers atomic.Bool
+)
+
+// SetDuplicateHandlerSuffix chooses what RegisterShutdownHandler does with a
+// name that is already taken: by default it returns ErrDuplicateHandler, with
+// on it logs a warning and registers the handler as "name#2", "name#3" and so
+// on.
+func SetDuplicateHandlerSuffix(on bool) {
+	suffixDuplicateHandlers.Store(on)
+}
+
+// RegisterShutdownHandler registers fn to run once shutdown begins.  Handlers
+// run one at a time in descending priority order, handlers of equal priority
+// in the order they were registered.  Each gets a context that expires after
+// timeout (no limit if timeout <= 0); a handler that fails, panics or times
+// out is logged and the remaining ones still run.  A handler that times out
+// is abandoned, not stopped.
+//
+// Registering after shutdown has begun returns ErrShutdownInProgress and the
+// handler never runs.  Names must be unique; see SetDuplicateHandlerSuffix.
+func RegisterShutdownHandler(name string, priority int, timeout time.Duration, fn func(ctx context.Context) error) error {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+
+	if InterruptRequested(shutdownDone()) {
+		return ErrShutdownInProgress
+	}
+	if handlerRegistered(name) {
+		if !suffixDuplicateHandlers.Load() {
+			return fmt.Errorf("%w: %s", ErrDuplicateHandler, name)
+		}
+		unique := name
+		for i := 2; handlerRegistered(unique); i++ {
+			unique = fmt.Sprintf("%s#%d", name, i)
+		}
+		logger().Warn("renamed duplicate shutdown handler", "handler", name, "registered", unique)
+		name = unique
+	}
+	handlers = append(handlers, shutdownHandler{name: name, priority: priority, timeout: timeout, fn: fn})
+	startShutdownHandlersLocked()
+	return nil
+}
+
+// ShutdownComplete returns a channel that is closed once shutdown has begun,
+// the drain before the handlers is over and every shutdown handler has
+// finished, which is when the orchestrated teardown is done.  On a forced
+// exit, after repeated signals or by the shutdown watchdog, the process ends
+// first and the channel never closes.
+func ShutdownComplete() <-chan struct{} {
+	return startShutdownHandlers()
+}
+
+// handlerRegistered reports whether a handler is registered under name.  It
+// must be called with handlersMu held.
+func handlerRegistered(name string) bool {
+	for _, h := range handlers {
+		if h.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// startShutdownHandlers arranges for the registered handlers to run once
+// shutdown begins and returns handlersDone, which is closed when they have
+// finished.
+func startShutdownHandlers() <-chan struct{} {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+
+	return startShutdownHandlersLocked()
+}
+
+func startShutdownHandlersLocked() <-chan struct{} {
+	finished := handlersDone
+	if handlersStarted {
+		return finished
+	}
+	handlersStarted = true
+
+	done := shutdownDone()
+	goWorker(func(quit <-chan struct{}) {
+		select {
+		case <-done:
+		case <-quit:
+			return
+		}
+		awaitIdleBeforeHandlers()
+
+		handlersMu.Lock()
+		hs := handlers
+		handlersMu.Unlock()
+
+		runShutdownHandlers(hs)
+		_, _, started := shutdownState()
+		shutdownFinished(time.Since(started))
+		close(finished)
+	})
+	return finished
+}
+
+func runShutdownHandlers(hs []shutdownHandler) {
+	hs = append([]shutdownHandler(nil), hs...)
+	sort.SliceStable(hs, func(i, j int) bool {
+		return hs[i].priority > hs[j].priority
+	})
+
+	for _, h := range hs {
+		start := time.Now()
+		if err := runShutdownHandler(h); err != nil {
+			logShutdownAt(logger().Error, "shutdown handler failed", "phase", "handlers", "handler", h.name,
+				"duration", time.Since(start), "err", err)
+			continue
+		}
+		logShutdownAt(logger().Info, "shutdown handler done", "phase", "handlers", "handler", h.name,
+			"duration", time.Since(start))
+	}
+}
+
+// runShutdownHandler runs h, turning a panic or an expired timeout into an
+// error.
+func runShutdownHandler(h shutdownHandler) error {
+	ctx := context.Background()
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+			}
+		}()
+		done <- h.fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %v", h.timeout)
+	}
+}
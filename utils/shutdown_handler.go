@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// ReasonHTTPRequest is the shutdown reason logged when ShutdownHandler
+// triggers a shutdown.
+const ReasonHTTPRequest = "http-request"
+
+// ShutdownHandler returns a handler that triggers a graceful shutdown on a POST
+// carrying "Authorization: Bearer <authToken>" and answers 202 Accepted.
+// Other methods get 405 and a missing or wrong token gets 403.  If authToken
+// is empty every request is refused with 403, so the endpoint can't be
+// exposed by accident.
+func ShutdownHandler(authToken string) http.Handler {
+	want := []byte("Bearer " + authToken)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		got := []byte(r.Header.Get("Authorization"))
+		if authToken == "" || subtle.ConstantTimeCompare(got, want) != 1 {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		requestShutdown(ReasonHTTPRequest, "remote", r.RemoteAddr)
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdownHandler(t *testing.T) {
+	resetSignals(t)
+
+	do := func(h http.Handler, method, auth string) int {
+		r := httptest.NewRequest(method, "/shutdown", nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+	h := ShutdownHandler("secret")
+	require.Equal(t, http.StatusMethodNotAllowed, do(h, http.MethodGet, "Bearer secret"))
+	require.Equal(t, http.StatusForbidden, do(h, http.MethodPost, ""))
+	require.Equal(t, http.StatusForbidden, do(h, http.MethodPost, "Bearer wrong"))
+	require.Equal(t, http.StatusForbidden, do(h, http.MethodPost, "secret"))
+	require.Equal(t, http.StatusForbidden, do(ShutdownHandler(""), http.MethodPost, "Bearer "))
+	require.False(t, InterruptRequested(NewDoneChannel()))
+
+	require.Equal(t, http.StatusAccepted, do(h, http.MethodPost, "Bearer secret"))
+	require.True(t, InterruptRequested(NewDoneChannel()))
+	_, reason, _ := shutdownState()
+	require.Equal(t, ReasonHTTPRequest, reason)
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdownHandlers(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		ran []string
+	)
+	handler := func(name string, priority int, timeout time.Duration, fn func(ctx context.Context) error) shutdownHandler {
+		return shutdownHandler{name: name, priority: priority, timeout: timeout, fn: func(ctx context.Context) error {
+			mu.Lock()
+			ran = append(ran, name)
+			mu.Unlock()
+			return fn(ctx)
+		}}
+	}
+	ok := func(context.Context) error { return nil }
+
+	start := time.Now()
+	runShutdownHandlers([]shutdownHandler{
+		handler("close-db", 10, 5*time.Second, ok),
+		handler("flush-traces", 100, 30*time.Second, ok),
+		handler("stuck", 50, 20*time.Millisecond, func(ctx context.Context) error {
+			select {} // ignores its context and is abandoned
+		}),
+		handler("failing", 50, time.Second, func(context.Context) error { return errors.New("boom") }),
+		handler("panicking", 20, time.Second, func(context.Context) error { panic("boom") }),
+		handler("cancelled", 20, 20*time.Millisecond, func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+	})
+
+	require.Equal(t, []string{"flush-traces", "stuck", "failing", "panicking", "cancelled", "close-db"}, ran)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestRunShutdownHandlerErrors(t *testing.T) {
+	err := runShutdownHandler(shutdownHandler{name: "panicking", fn: func(context.Context) error { panic("boom") }})
+	require.ErrorContains(t, err, "panic: boom")
+
+	err = runShutdownHandler(shutdownHandler{name: "slow", timeout: 10 * time.Millisecond, fn: func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}})
+	require.ErrorContains(t, err, "timed out")
+}
+
+func TestDuplicateShutdownHandler(t *testing.T) {
+	resetSignals(t)
+	ok := func(context.Context) error { return nil }
+
+	require.NoError(t, RegisterShutdownHandler("db", 0, 0, ok))
+	require.ErrorIs(t, RegisterShutdownHandler("db", 0, 0, ok), ErrDuplicateHandler)
+
+	SetDuplicateHandlerSuffix(true)
+	require.NoError(t, RegisterShutdownHandler("db", 0, 0, ok))
+	require.NoError(t, RegisterShutdownHandler("db", 0, 0, ok))
+
+	handlersMu.Lock()
+	var names []string
+	for _, h := range handlers {
+		names = append(names, h.name)
+	}
+	handlersMu.Unlock()
+	require.Equal(t, []string{"db", "db#2", "db#3"}, names)
+}
+
+func TestShutdownComplete(t *testing.T) {
+	resetSignals(t)
+
+	release := make(chan struct{})
+	require.NoError(t, RegisterShutdownHandler("block", 0, 0, func(context.Context) error {
+		<-release
+		return nil
+	}))
+	complete := ShutdownComplete()
+	RequestShutdown("test")
+	select {
+	case <-complete:
+		t.Fatal("complete while a handler is still running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-complete:
+	case <-time.After(time.Second):
+		t.Fatal("not complete after the handlers finished")
+	}
+	require.Equal(t, complete, ShutdownComplete())
+}
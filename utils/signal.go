@@ -1,58 +1,620 @@
 package utils
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/ethereum/go-ethereum/event"
-	"github.com/ethereum/go-ethereum/log"
 )
 
-// shutdownRequestChannel is used to initiate shutdown from one of the
-// subsystems using the same code paths as when an interrupt signal is received.
-var shutdownRequestChannel = make(chan struct{})
+// shutdownChannel is closed exactly once by the first interrupt signal or
+// shutdown request.  Listeners started after that point see it closed and
+// return immediately instead of blocking forever.  The variables are replaced
+// by ResetForTesting, so they are only accessed with shutdownMu held, usually
+// through shutdownDone and shutdownState.  shutdownBegun is only set with
+// shutdownMu held but may be loaded without it, for hot paths.
+var (
+	shutdownMu      sync.Mutex
+	shutdownChannel = make(chan struct{})
+	shutdownBegun   atomic.Bool
+
+	// shutdownSignal is the signal that triggered shutdown, or nil for a
+	// shutdown request, shutdownReason the logged reason and
+	// shutdownStarted is when that happened.
+	shutdownSignal  os.Signal
+	shutdownReason  string
+	shutdownStarted time.Time
+)
+
+// shutdownDone returns the channel that is closed when shutdown begins.
+func shutdownDone() <-chan struct{} {
+	shutdownMu.Lock()
+	defer shutdownMu.Unlock()
+
+	return shutdownChannel
+}
+
+// shutdownState returns the signal and reason that triggered shutdown and when
+// that happened.  It must only be called after shutdownDone is closed.
+func shutdownState() (sig os.Signal, reason string, started time.Time) {
+	shutdownMu.Lock()
+	defer shutdownMu.Unlock()
+
+	return shutdownSignal, shutdownReason, shutdownStarted
+}
+
+// Shutdown log lines carry the following key/value fields so that log
+// pipelines can filter and aggregate on them.  The names are stable.
+//
+//	component  always "shutdown"
+//	reason     "signal" for OS signals, otherwise the reason given to
+//	           RequestShutdown or one of the Reason constants
+//	sig        canonical signal name (see signalName), when reason=signal
+//	elapsed    time since shutdown began, on repeated events
+//	phase      "drain" while waiting for Tracker work, "handlers" while
+//	           the shutdown handlers run, "components" for the components
+//	           run by RunUntilInterrupt
+//	handler    shutdown handler or component name, in phase=handlers and
+//	           phase=components
+//	duration   how long that handler or component took
+//	work       name of unfinished Tracker work, in phase=drain
+//	age        how long that work has been running
+//	err        why a handler or component failed
+//
+// Other fields are specific to a single message.
+func logShutdown(msg string, ctx ...interface{}) {
+	logShutdownAt(logger().Warn, msg, ctx...)
+}
+
+// logShutdownAt is logShutdown at the level of log, one of the Logger
+// methods.
+func logShutdownAt(log func(msg string, ctx ...interface{}), msg string, ctx ...interface{}) {
+	log(msg, append([]interface{}{"component", "shutdown"}, ctx...)...)
+}
+
+// notifyShutdown records sig and reason and closes shutdownChannel.  Only the
+// first call has any effect, so concurrent or repeated triggers never close an
+// already closed channel.  That call runs announce, if not nil, before
+// anyone waiting for shutdown is released, so that the line announcing it is
+// logged first.  It reports whether this call was the one that triggered
+// shutdown.
+func notifyShutdown(sig os.Signal, reason string, announce func()) bool {
+	shutdownMu.Lock()
+	if shutdownBegun.Load() {
+		shutdownMu.Unlock()
+		return false
+	}
+	shutdownBegun.Store(true)
+	shutdownSignal = sig
+	shutdownReason = reason
+	shutdownStarted = time.Now()
+	interruptCount.Store(1)
+	c := shutdownChannel
+	shutdownMu.Unlock()
+
+	if announce != nil {
+		announce()
+	}
+	close(c)
+	shutdownBegan()
+	closeDoneChannels()
+	return true
+}
 
 // interruptSignals defines the default signals to catch in order to do a proper
-// shutdown.  This may be modified during init depending on the platform.
-var interruptSignals = []os.Signal{os.Interrupt}
+// shutdown.  It is extended with platformSignals during init and can be
+// replaced with SetInterruptSignals until the dispatcher starts.
+var (
+	signalsMu         sync.Mutex
+	interruptSignals  = append([]os.Signal{os.Interrupt}, platformSignals...)
+	dispatcherStarted bool
+)
+
+// SetInterruptSignals replaces the signals that trigger shutdown.  It must be
+// called before any listener is started and returns an error otherwise, or if
+// signals is empty.
+func SetInterruptSignals(signals []os.Signal) error {
+	if len(signals) == 0 {
+		return errors.New("no interrupt signals given")
+	}
+
+	signalsMu.Lock()
+	defer signalsMu.Unlock()
+
+	if dispatcherStarted {
+		return errors.New("interrupt listener already running")
+	}
+	interruptSignals = append([]os.Signal(nil), signals...)
+	return nil
+}
+
+// RequestShutdown initiates shutdown from one of the subsystems using the same
+// code paths as when an interrupt signal is received.  It may be called any
+// number of times from any goroutine; only the first call triggers shutdown
+// and later ones are just logged.  It never blocks, and a request made before
+// any listener has been started is delivered once one starts.
+func RequestShutdown(reason string) {
+	requestShutdown(reason)
+}
+
+// requestShutdown is RequestShutdown with extra key/value pairs for the log.
+// It reports whether this request was the one that triggered shutdown.
+func requestShutdown(reason string, ctx ...interface{}) bool {
+	ctx = append([]interface{}{"reason", reason}, ctx...)
+	if notifyShutdown(nil, reason, func() { logShutdown("received shutdown request", ctx...) }) {
+		return true
+	}
+	_, _, started := shutdownState()
+	logShutdown("received shutdown request (repeated)", append(ctx, "elapsed", time.Since(started))...)
+	publishRepeated(nil, reason)
+	return false
+}
+
+// signalHandlingDisabled is set by WithoutSignalHandling.
+var signalHandlingDisabled atomic.Bool
+
+// WithoutSignalHandling turns off OS signal handling for programs that embed
+// this package inside a host which owns all signal handling.  Listeners
+// started afterwards never call signal.Notify; shutdown is then only
+// triggered programmatically, and the listener channels, InterruptFeed and
+// the other notifications behave exactly as they do for a signal.  It must be
+// called before any listener is started.
+func WithoutSignalHandling() {
+	signalHandlingDisabled.Store(true)
+}
+
+// Notifier registers channels for OS signals.  The default forwards to
+// signal.Notify and signal.Stop; tests can replace it with SetNotifier to
+// observe registrations, and inject signals with SimulateInterrupt.
+type Notifier interface {
+	Notify(c chan<- os.Signal, sig ...os.Signal)
+	Stop(c chan<- os.Signal)
+}
+
+type osNotifier struct{}
+
+func (osNotifier) Notify(c chan<- os.Signal, sig ...os.Signal) { signal.Notify(c, sig...) }
+func (osNotifier) Stop(c chan<- os.Signal)                     { signal.Stop(c) }
+
+// notifier is the current Notifier, guarded by signalsMu.
+var notifier Notifier = osNotifier{}
+
+// SetNotifier replaces the Notifier used to register for signals.  Like
+// SetInterruptSignals it must be called before any listener is started.
+func SetNotifier(n Notifier) error {
+	signalsMu.Lock()
+	defer signalsMu.Unlock()
+
+	if dispatcherStarted {
+		return errors.New("interrupt listener already running")
+	}
+	notifier = n
+	return nil
+}
+
+// controlSignal is a signal that is passed to handle instead of triggering
+// shutdown.  kind names it in the log.
+type controlSignal struct {
+	kind   string
+	handle func(sig os.Signal)
+}
+
+// controlSignals maps reloadSignals, pauseSignal and resumeSignal to their
+// handlers, leaving out any that are also interrupt signals.  The caller must
+// hold signalsMu.
+func controlSignals() map[os.Signal]controlSignal {
+	control := make(map[os.Signal]controlSignal)
+	for _, sig := range reloadSignals {
+		control[sig] = controlSignal{"reload", requestReload}
+	}
+	if pauseSignal != nil {
+		control[pauseSignal] = controlSignal{"pause", func(sig os.Signal) { setPaused(true, sig) }}
+	}
+	if resumeSignal != nil {
+		control[resumeSignal] = controlSignal{"resume", func(sig os.Signal) { setPaused(false, sig) }}
+	}
+	for _, sig := range interruptSignals {
+		delete(control, sig)
+	}
+	return control
+}
+
+// notifySignals registers c for interruptSignals and the control signals
+// unless signal handling has been disabled, in which case c never receives
+// anything.  The caller must hold signalsMu.
+func notifySignals(c chan<- os.Signal, control map[os.Signal]controlSignal) {
+	if signalHandlingDisabled.Load() {
+		return
+	}
+	signals := append([]os.Signal(nil), interruptSignals...)
+	for sig := range control {
+		signals = append(signals, sig)
+	}
+	notifier.Notify(c, signals...)
+}
+
+// The dispatcher is the single process-wide signal registration, guarded by
+// signalsMu.  All listeners share the one dispatcher goroutine, so every one
+// of them observes the same first interrupt no matter how many there are or
+// how shutdown was triggered.  dispatcherSignals is the channel registered
+// with the notifier, and listening is closed once that has happened, so that
+// tests can send signals without racing it.
+var (
+	dispatcherSignals chan os.Signal
+	listening         = make(chan struct{})
+)
+
+// startDispatcher registers for interrupt signals and starts the dispatcher
+// goroutine, once per process or ResetForTesting.
+func startDispatcher() {
+	signalsMu.Lock()
+	defer signalsMu.Unlock()
+
+	if dispatcherStarted {
+		return
+	}
+	dispatcherStarted = true
+
+	signals := make(chan os.Signal, 1)
+	control := controlSignals()
+	notifySignals(signals, control)
+	dispatcherSignals = signals
+	close(listening)
+	registerProcessStart()
+	// Even with no handlers registered the end of shutdown is recorded in
+	// the stats.
+	startShutdownHandlers()
+
+	done := shutdownDone()
+	goWorker(deliverReloads)
+	goWorker(deliverPauses)
+	goWorker(func(quit <-chan struct{}) { dispatch(signals, control, done, quit) })
+}
+
+// SimulateInterrupt hands sig to the dispatcher exactly as if the OS had
+// delivered it, starting the dispatcher if necessary, so tests can exercise
+// shutdown, repeated signal, reload and pause handling without sending real
+// signals.
+// It blocks until the dispatcher has room for the signal.
+func SimulateInterrupt(sig os.Signal) {
+	startDispatcher()
+
+	signalsMu.Lock()
+	signals := dispatcherSignals
+	signalsMu.Unlock()
+
+	signals <- sig
+}
+
+// workers tracks the goroutines the package runs for itself, so that
+// ResetForTesting can stop them by closing workersQuit and wait for them.
+var (
+	workersMu   sync.Mutex
+	workersQuit = make(chan struct{})
+	workers     sync.WaitGroup
+)
+
+// goWorker runs fn on a new worker goroutine.  fn must return soon after quit
+// is closed.
+func goWorker(fn func(quit <-chan struct{})) {
+	workersMu.Lock()
+	quit := workersQuit
+	workers.Add(1)
+	workersMu.Unlock()
 
-// interruptListener listens for OS Signals such as SIGINT (Ctrl+C) and shutdown
-// requests from shutdownRequestChannel.  It returns a channel that is closed
-// when either signal is received.
-func InterruptListener() <-chan struct{} {
-	c := make(chan struct{})
 	go func() {
-		interruptChannel := make(chan os.Signal, 1)
-		signal.Notify(interruptChannel, interruptSignals...)
+		defer workers.Done()
+		fn(quit)
+	}()
+}
 
-		// Listen for initial shutdown signal and close the returned
-		// channel to notify the caller.
+// dispatch turns the first interrupt signal into a shutdown, unless a shutdown
+// request got there first, and then reports the repeated ones.  Control
+// signals are passed on until shutdown begins and ignored afterwards.
+func dispatch(signals <-chan os.Signal, control map[os.Signal]controlSignal, done, quit <-chan struct{}) {
+	count := 0
+wait:
+	for {
 		select {
-		case sig := <-interruptChannel:
-			log.Warn("received signal", "sig", sig.String())
+		case sig := <-signals:
+			if c, ok := control[sig]; ok {
+				c.handle(sig)
+				continue
+			}
+			countSignal(sig)
+			if parentDied(sig) {
+				parentExited()
+			} else {
+				logShutdown("received signal", "reason", "signal", "sig", signalName(sig))
+				notifyShutdown(sig, "signal", nil)
+			}
+			count++
+			break wait
+
+		case <-done:
+			break wait
 
-		case <-shutdownRequestChannel:
-			log.Warn("received shutdown request")
+		case <-quit:
+			return
 		}
-		close(c)
+	}
 
-		// Listen for repeated signals and display a message so the user
-		// knows the shutdown is in progress and the process is not
-		// hung.
-		for {
-			select {
-			case sig := <-interruptChannel:
-				log.Warn("received signal (repeated)", "sig", sig.String())
+	// Listen for repeated signals and display a message so the user
+	// knows the shutdown is in progress and the process is not
+	// hung.
+	for {
+		var sig os.Signal
+		select {
+		case sig = <-signals:
+		case <-quit:
+			return
+		}
+		if c, ok := control[sig]; ok {
+			logShutdown("ignored "+c.kind+" signal during shutdown", "reason", "signal", "sig", signalName(sig))
+			continue
+		}
+		_, _, started := shutdownState()
+		logShutdown("received signal (repeated)", "reason", "signal", "sig", signalName(sig),
+			"elapsed", time.Since(started))
+		countSignal(sig)
+		count++
+		repeatedSignal(sig, count)
+		publishRepeated(sig, "signal")
+		forceExitIfNeeded(count)
+	}
+}
 
-			case <-shutdownRequestChannel:
-				log.Warn("received shutdown request (repeated)")
-			}
+// ErrShutdownSignal is the cancellation cause of contexts from WithInterrupt
+// when an OS signal triggered shutdown; errors.As extracts the signal.  Any
+// ErrShutdownSignal matches another with errors.Is, so
+// errors.Is(err, ErrShutdownSignal{}) tests for the kind of cause.
+type ErrShutdownSignal struct {
+	Signal os.Signal
+}
+
+func (e ErrShutdownSignal) Error() string {
+	if e.Signal == nil {
+		return "shutdown signal received"
+	}
+	return "shutdown signal received: " + signalName(e.Signal)
+}
+
+// Is reports whether target is an ErrShutdownSignal.
+func (e ErrShutdownSignal) Is(target error) bool {
+	switch target.(type) {
+	case ErrShutdownSignal, *ErrShutdownSignal:
+		return true
+	}
+	return false
+}
+
+// ErrShutdownRequested is the cancellation cause of contexts from
+// WithInterrupt when RequestShutdown triggered shutdown; errors.As extracts
+// the reason.  Any ErrShutdownRequested matches another with errors.Is.
+type ErrShutdownRequested struct {
+	Reason string
+}
+
+func (e ErrShutdownRequested) Error() string {
+	if e.Reason == "" {
+		return "shutdown requested"
+	}
+	return "shutdown requested: " + e.Reason
+}
+
+// Is reports whether target is an ErrShutdownRequested.
+func (e ErrShutdownRequested) Is(target error) bool {
+	switch target.(type) {
+	case ErrShutdownRequested, *ErrShutdownRequested:
+		return true
+	}
+	return false
+}
+
+// shutdownCause describes why shutdown was triggered.  It must only be called
+// after shutdownDone is closed.
+func shutdownCause() error {
+	sig, reason, _ := shutdownState()
+	if sig != nil {
+		return ErrShutdownSignal{Signal: sig}
+	}
+	return ErrShutdownRequested{Reason: reason}
+}
+
+// WithInterrupt returns a copy of parent that is cancelled when an interrupt
+// signal or shutdown request arrives, or when the returned cancel function is
+// called, whichever happens first.  context.Cause reports which: it is an
+// ErrShutdownSignal or an ErrShutdownRequested on shutdown.  Signals are
+// registered only once no matter how many contexts are created, and nothing
+// is left running once the context is done.
+func WithInterrupt(parent context.Context) (context.Context, context.CancelFunc) {
+	startDispatcher()
+
+	ctx, cancel := context.WithCancelCause(parent)
+	done := shutdownDone()
+	go func() {
+		select {
+		case <-done:
+			cancel(shutdownCause())
+		case <-ctx.Done():
 		}
 	}()
+	return ctx, func() { cancel(context.Canceled) }
+}
+
+// InterruptContext returns a context that is cancelled when an interrupt
+// signal or shutdown request arrives.  It is meant to live as long as the
+// process; use WithInterrupt for shorter lived contexts.
+func InterruptContext() context.Context {
+	ctx, _ := WithInterrupt(context.Background())
+	return ctx
+}
+
+// signalNames maps signals to the canonical names used in logs and metric
+// labels, since sig.String() differs between platforms ("interrupt" vs
+// "SIGINT").
+var signalNames = map[os.Signal]string{
+	os.Interrupt:    "SIGINT",
+	syscall.SIGTERM: "SIGTERM",
+	syscall.SIGQUIT: "SIGQUIT",
+}
+
+// signalName returns the canonical name of sig, falling back to sig.String()
+// for signals without a known name.
+func signalName(sig os.Signal) string {
+	if name, ok := signalNames[sig]; ok {
+		return name
+	}
+	return sig.String()
+}
+
+// interruptListener listens for OS Signals such as SIGINT (Ctrl+C) and shutdown
+// requests from RequestShutdown.  It returns a channel that is closed when
+// either signal is received.  Every call returns its own channel; all of them
+// are closed by the same first interrupt.
+func InterruptListener() <-chan struct{} {
+	startDispatcher()
+	return NewDoneChannel()
+}
+
+// doneChannels tracks the channels handed out by NewDoneChannel so that they
+// can all be closed from the single shutdown trigger.
+var (
+	doneMu       sync.Mutex
+	doneChannels = make(map[<-chan struct{}]chan struct{})
+	doneClosed   bool
+)
+
+func closeDoneChannels() {
+	doneMu.Lock()
+	defer doneMu.Unlock()
+
+	for k, c := range doneChannels {
+		close(c)
+		delete(doneChannels, k)
+	}
+	doneClosed = true
+}
 
+// NewDoneChannel returns a new channel that is closed when shutdown begins.
+// Every call returns a distinct channel, so subsystems don't have to share
+// one.  If shutdown has already begun the returned channel is closed.
+func NewDoneChannel() <-chan struct{} {
+	doneMu.Lock()
+	defer doneMu.Unlock()
+
+	c := make(chan struct{})
+	if doneClosed {
+		close(c)
+		return c
+	}
+	doneChannels[c] = c
 	return c
 }
 
+// ReleaseDoneChannel stops tracking a channel returned by NewDoneChannel so it
+// can be garbage collected.  The channel is left open; releasing an unknown or
+// already closed channel is a no-op.
+func ReleaseDoneChannel(done <-chan struct{}) {
+	doneMu.Lock()
+	defer doneMu.Unlock()
+
+	delete(doneChannels, done)
+}
+
+// repeatedCallbacks are the functions registered with OnRepeatedSignal.
+var (
+	repeatedMu        sync.Mutex
+	repeatedCallbacks []func(sig os.Signal, count int)
+)
+
+// OnRepeatedSignal registers fn to be called for every interrupt signal that
+// arrives after shutdown has begun.  count is the number of signals received
+// so far, including this one.  fn runs on its own goroutine so that a slow
+// callback never holds up signal handling.
+func OnRepeatedSignal(fn func(sig os.Signal, count int)) {
+	repeatedMu.Lock()
+	defer repeatedMu.Unlock()
+
+	repeatedCallbacks = append(repeatedCallbacks, fn)
+}
+
+func repeatedSignal(sig os.Signal, count int) {
+	repeatedMu.Lock()
+	callbacks := repeatedCallbacks
+	repeatedMu.Unlock()
+
+	for _, fn := range callbacks {
+		go fn(sig, count)
+	}
+}
+
+// WaitForShutdown blocks until shutdown has been triggered and every handler
+// registered with RegisterShutdownHandler has finished or timed out.  It
+// returns the signal that triggered shutdown, or nil if it was a shutdown
+// request.  Signals are only handled once a listener has been started.
+func WaitForShutdown() os.Signal {
+	sig, _ := WaitForShutdownComplete(context.Background())
+	return sig
+}
+
+// WaitForShutdownCtx blocks until shutdown has been triggered and returns the
+// signal that triggered it, or nil for a shutdown request.  It gives up and
+// returns ctx.Err() once ctx is done.  Unlike WaitForShutdown it doesn't wait
+// for the shutdown handlers, so it is safe to use in work that a handler waits
+// for.
+func WaitForShutdownCtx(ctx context.Context) (os.Signal, error) {
+	select {
+	case <-shutdownDone():
+		sig, _, _ := shutdownState()
+		return sig, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// WaitForShutdownComplete is like WaitForShutdownCtx but also waits for every
+// registered shutdown handler to finish or time out.
+func WaitForShutdownComplete(ctx context.Context) (os.Signal, error) {
+	select {
+	case <-startShutdownHandlers():
+		sig, _, _ := shutdownState()
+		return sig, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Await is WaitForShutdownCtx for callers that only care about errors: it
+// returns nil once shutdown has been triggered and ctx.Err() if ctx is done
+// first.
+func Await(ctx context.Context) error {
+	_, err := WaitForShutdownCtx(ctx)
+	return err
+}
+
+// RemainingGrace returns how much time is left before ctx's deadline, or false
+// if ctx has no deadline.  The result is never negative.
+func RemainingGrace(ctx context.Context) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	if d := time.Until(deadline); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
 // interruptRequested returns true when the channel returned by
 // interruptListener was closed.  This simplifies early shutdown slightly since
 // the caller can just use an if statement instead of a select.
@@ -66,38 +628,209 @@ func InterruptRequested(interrupted <-chan struct{}) bool {
 	return false
 }
 
-// interruptListener listens for OS Signals such as SIGINT (Ctrl+C) and shutdown
-// requests from shutdownRequestChannel.  It returns a channel that is closed
-// when either signal is received.
-var InterruptFeed = event.Feed{}
+// InterruptEvent is sent on InterruptFeed for the interrupt that triggered
+// shutdown and, with Repeated set, for every signal or shutdown request after
+// it.  Count numbers them: 1 for the first, then increasing by one for every
+// later signal or request, including any whose event was dropped.
+//
+// InterruptFeed used to carry struct{}{}.  Subscribers should move to
+// SubscribeInterrupt, or subscribe with a chan InterruptEvent; until then
+// LegacyInterruptFeed keeps sending struct{}{} for the first interrupt.
+type InterruptEvent struct {
+	Signal   os.Signal // nil for a shutdown request
+	Reason   string    // "signal" or the reason given to RequestShutdown
+	Time     time.Time
+	Repeated bool // Count > 1
+	Count    int
+}
+
+var (
+	// InterruptFeed receives an InterruptEvent when an OS signal such as
+	// SIGINT (Ctrl+C) or a shutdown request from RequestShutdown is
+	// observed, once StartInterrupteListener has been called.  A direct
+	// subscriber that doesn't receive holds up the other direct subscribers,
+	// and once 16 events are queued behind it later ones are dropped for
+	// all of them.  New code should use SubscribeInterrupt instead.
+	InterruptFeed = event.Feed{}
+
+	// Deprecated: LegacyInterruptFeed sends struct{}{} for the first
+	// interrupt, as InterruptFeed did before it carried InterruptEvent.  It
+	// will be removed in the next release.
+	LegacyInterruptFeed = event.Feed{}
+
+	feedStarted atomic.Bool
+	feedEvents  = make(chan InterruptEvent, 16)
+
+	// interruptCount is the Count of the latest InterruptEvent, set to 1
+	// by the interrupt that triggers shutdown.
+	interruptCount atomic.Int64
+
+	// slowSubscriberWarning is how long a subscriber may leave an event
+	// unreceived before it is logged as not keeping up.
+	slowSubscriberWarning = time.Second
+)
 
+// StartInterrupteListener starts delivering interrupts to InterruptFeed.  An
+// interrupt that happened before the call is delivered too.  Calling it more
+// than once has no further effect.
 func StartInterrupteListener() {
-	go func() {
-		interruptChannel := make(chan os.Signal, 1)
-		signal.Notify(interruptChannel, interruptSignals...)
+	startDispatcher()
+	if feedStarted.CompareAndSwap(false, true) {
+		done := shutdownDone()
+		goWorker(func(quit <-chan struct{}) { deliverInterrupts(done, quit) })
+	}
+}
 
-		// Listen for initial shutdown signal and close the returned
-		// channel to notify the caller.
-		select {
-		case sig := <-interruptChannel:
-			log.Warn("received signal", "sig", sig.String())
+// SubscribeInterrupt returns a channel of the given buffer size that receives
+// the same events as InterruptFeed.  Unlike a direct InterruptFeed
+// subscription, every channel is fed by its own goroutine, so a subscriber
+// that stops receiving only delays itself and is logged, under the
+// subscriber's file and line, once it falls behind.  Subscribing starts the
+// listener as StartInterrupteListener does, and a channel subscribed after
+// shutdown began still receives the first event.
+func SubscribeInterrupt(buffer int) (<-chan InterruptEvent, event.Subscription) {
+	name := "unknown"
+	if _, file, line, ok := runtime.Caller(1); ok {
+		name = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	}
+	return SubscribeInterruptNamed(name, buffer)
+}
 
-		case <-shutdownRequestChannel:
-			log.Warn("received shutdown request")
-		}
-		InterruptFeed.Send(struct{}{})
+// SubscribeInterruptNamed is SubscribeInterrupt with the name used to report a
+// subscriber that isn't keeping up.
+func SubscribeInterruptNamed(name string, buffer int) (<-chan InterruptEvent, event.Subscription) {
+	c := make(chan InterruptEvent, buffer)
+	queue := make(chan InterruptEvent, 16)
+
+	StartInterrupteListener()
+	subscribersMu.Lock()
+	subscribers[queue] = name
+	if latchedInterrupt != nil {
+		queue <- *latchedInterrupt
+	}
+	subscribersMu.Unlock()
 
-		// Listen for repeated signals and display a message so the user
-		// knows the shutdown is in progress and the process is not
-		// hung.
+	return c, event.NewSubscription(func(quit <-chan struct{}) error {
+		defer func() {
+			subscribersMu.Lock()
+			delete(subscribers, queue)
+			subscribersMu.Unlock()
+		}()
 		for {
+			var ev InterruptEvent
 			select {
-			case sig := <-interruptChannel:
-				log.Warn("received signal (repeated)", "sig", sig.String())
-
-			case <-shutdownRequestChannel:
-				log.Warn("received shutdown request (repeated)")
+			case ev = <-queue:
+			case <-quit:
+				return nil
+			}
+			warn := warnSlowSubscriber(name)
+			select {
+			case c <- ev:
+				warn.Stop()
+			case <-quit:
+				warn.Stop()
+				return nil
 			}
 		}
-	}()
+	})
+}
+
+// subscribers maps the queue of every SubscribeInterrupt channel to its name,
+// and latchedInterrupt holds the first event once it has been published, for
+// channels subscribed after it.
+var (
+	subscribersMu    sync.Mutex
+	subscribers      = make(map[chan InterruptEvent]string)
+	latchedInterrupt *InterruptEvent
+)
+
+// warnSlowSubscriber logs that the named subscriber isn't keeping up unless
+// the returned timer is stopped within slowSubscriberWarning.
+func warnSlowSubscriber(name string) *time.Timer {
+	return time.AfterFunc(slowSubscriberWarning, func() {
+		logShutdown("interrupt subscriber is not receiving", "subscriber", name, "waited", slowSubscriberWarning)
+	})
+}
+
+// publishInterrupt queues ev for every SubscribeInterrupt channel without
+// blocking, and latches it if it is the first.
+func publishInterrupt(ev InterruptEvent) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+
+	if !ev.Repeated {
+		latchedInterrupt = &ev
+	}
+
+	for queue, name := range subscribers {
+		select {
+		case queue <- ev:
+		default:
+			logShutdown("dropped interrupt event, subscriber is backed up", "reason", ev.Reason, "subscriber", name)
+		}
+	}
+}
+
+// deliverInterrupts hands every event to the SubscribeInterrupt channels
+// directly and to InterruptFeed and LegacyInterruptFeed through a sendFeed
+// goroutine each, since a single direct feed subscriber that doesn't receive
+// blocks Feed.Send.  Events that don't fit in a feed's queue are dropped.
+func deliverInterrupts(done, quit <-chan struct{}) {
+	select {
+	case <-done:
+	case <-quit:
+		return
+	}
+	feed := make(chan InterruptEvent, cap(feedEvents))
+	legacy := make(chan struct{}, 1)
+	goWorker(func(quit <-chan struct{}) { sendFeed("InterruptFeed", &InterruptFeed, feed, quit) })
+	goWorker(func(quit <-chan struct{}) { sendFeed("LegacyInterruptFeed", &LegacyInterruptFeed, legacy, quit) })
+
+	sig, reason, started := shutdownState()
+	ev := InterruptEvent{Signal: sig, Reason: reason, Time: started, Count: 1}
+	legacy <- struct{}{}
+	for {
+		publishInterrupt(ev)
+		select {
+		case feed <- ev:
+		default:
+			logShutdown("dropped repeated interrupt event, InterruptFeed is backed up", "reason", ev.Reason)
+		}
+
+		select {
+		case ev = <-feedEvents:
+		case <-quit:
+			return
+		}
+	}
+}
+
+// sendFeed sends events to feed, the only feed this goroutine serves, so a
+// subscriber that doesn't receive holds up only the feed it subscribed to.
+func sendFeed[T any](name string, feed *event.Feed, events <-chan T, quit <-chan struct{}) {
+	for {
+		select {
+		case ev := <-events:
+			warn := warnSlowSubscriber(name)
+			feed.Send(ev)
+			warn.Stop()
+		case <-quit:
+			return
+		}
+	}
+}
+
+// publishRepeated queues a repeated interrupt for InterruptFeed without ever
+// blocking the caller.  Nothing is queued until StartInterrupteListener has
+// been called.
+func publishRepeated(sig os.Signal, reason string) {
+	count := int(interruptCount.Add(1))
+	if !feedStarted.Load() {
+		return
+	}
+	select {
+	case feedEvents <- InterruptEvent{Signal: sig, Reason: reason, Time: time.Now(), Repeated: true, Count: count}:
+	default:
+		logShutdown("dropped repeated interrupt event, feed is backed up", "reason", reason)
+	}
 }
@@ -0,0 +1,19 @@
+//go:build !unix && !windows
+
+package utils
+
+import "os"
+
+// platformSignals is empty on platforms such as js/wasm, which deliver no
+// signals besides os.Interrupt.
+var platformSignals []os.Signal
+
+// reloadSignals is empty because there is no SIGHUP.
+var reloadSignals []os.Signal
+
+// pauseSignal and resumeSignal are nil; use RequestPause and RequestResume
+// instead.
+var pauseSignal, resumeSignal os.Signal
+
+// diagnosticsSignal is nil; use DumpDiagnostics instead.
+var diagnosticsSignal os.Signal
@@ -0,0 +1,521 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNotifier stands in for signal.Notify so tests never register for real
+// OS signals.  Signals are injected with send, or SimulateInterrupt for the
+// dispatcher.
+type fakeNotifier struct {
+	mu       sync.Mutex
+	notifies int
+	stops    int
+	channels map[chan<- os.Signal][]os.Signal
+}
+
+func (n *fakeNotifier) Notify(c chan<- os.Signal, sig ...os.Signal) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifies++
+	if n.channels == nil {
+		n.channels = make(map[chan<- os.Signal][]os.Signal)
+	}
+	n.channels[c] = append(n.channels[c], sig...)
+}
+
+func (n *fakeNotifier) Stop(c chan<- os.Signal) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.stops++
+	delete(n.channels, c)
+}
+
+// send delivers sig to every channel registered for it, dropping it where the
+// channel is full like signal.Notify does.
+func (n *fakeNotifier) send(sig os.Signal) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for c, sigs := range n.channels {
+		for _, s := range sigs {
+			if s == sig {
+				select {
+				case c <- sig:
+				default:
+				}
+			}
+		}
+	}
+}
+
+func (n *fakeNotifier) counts() (notifies, stops int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.notifies, n.stops
+}
+
+// resetSignals gives the test fresh package state with a fakeNotifier
+// installed, and resets it again once the test is done.
+func resetSignals(t *testing.T) *fakeNotifier {
+	ResetForTesting()
+	t.Cleanup(ResetForTesting)
+
+	n := new(fakeNotifier)
+	require.NoError(t, SetNotifier(n))
+	return n
+}
+
+// waitListening blocks until a listener has registered its signal notifier.
+func waitListening(t *testing.T) {
+	select {
+	case <-listening:
+	case <-time.After(time.Second):
+		t.Fatal("listener did not start")
+	}
+}
+
+func TestWaitForShutdownCtxCancelled(t *testing.T) {
+	resetSignals(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	sig, err := WaitForShutdownCtx(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Nil(t, sig)
+}
+
+func TestAwaitDuringHandlers(t *testing.T) {
+	resetSignals(t)
+
+	// A worker waiting with Await must be released before the handler
+	// that waits for it runs, or shutdown never completes.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, Await(ctx), context.DeadlineExceeded)
+
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		if err := Await(context.Background()); err != nil {
+			t.Error(err)
+		}
+	}()
+	require.NoError(t, RegisterShutdownHandler("wait-for-worker", 0, 0, func(context.Context) error {
+		<-workerDone
+		return nil
+	}))
+
+	RequestShutdown("test")
+	finished := make(chan struct{})
+	go func() {
+		WaitForShutdown()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("shutdown handler waiting for an Await caller never finished")
+	}
+	sig, err := WaitForShutdownCtx(context.Background())
+	require.NoError(t, err)
+	require.Nil(t, sig)
+}
+
+func TestRemainingGrace(t *testing.T) {
+	d, ok := RemainingGrace(context.Background())
+	require.False(t, ok)
+	require.Zero(t, d)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	d, ok = RemainingGrace(ctx)
+	require.True(t, ok)
+	require.Positive(t, d)
+	require.LessOrEqual(t, d, time.Minute)
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	d, ok = RemainingGrace(expired)
+	require.True(t, ok)
+	require.Zero(t, d)
+}
+
+func TestWithInterruptParentCancel(t *testing.T) {
+	n := resetSignals(t)
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel := WithInterrupt(parent)
+	defer cancel()
+	other, cancelOther := WithInterrupt(context.Background())
+	InterruptContext()
+
+	cancelParent()
+	cancelOther()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context not cancelled with its parent")
+	}
+	require.ErrorIs(t, context.Cause(ctx), context.Canceled)
+	require.ErrorIs(t, context.Cause(other), context.Canceled)
+	notifies, _ := n.counts()
+	require.Equal(t, 1, notifies)
+}
+
+func TestRequestShutdown(t *testing.T) {
+	n := resetSignals(t)
+	ctx, cancel := WithInterrupt(context.Background())
+	defer cancel()
+	var handled atomic.Bool
+	require.NoError(t, RegisterShutdownHandler("test", 0, time.Second, func(context.Context) error {
+		handled.Store(true)
+		return nil
+	}))
+	done1, done2, released := NewDoneChannel(), NewDoneChannel(), NewDoneChannel()
+	require.NotEqual(t, done1, done2)
+	ReleaseDoneChannel(released)
+
+	legacy := make(chan struct{}, 1)
+	legacySub := LegacyInterruptFeed.Subscribe(legacy)
+	defer legacySub.Unsubscribe()
+
+	// Request shutdown twice, concurrently, before any listener runs.
+	var wg sync.WaitGroup
+	for _, reason := range []string{"first", "second"} {
+		wg.Add(1)
+		go func(reason string) {
+			defer wg.Done()
+			RequestShutdown(reason)
+		}(reason)
+	}
+	wg.Wait()
+	events, sub := SubscribeInterrupt(2)
+	defer sub.Unsubscribe()
+
+	listeners := []<-chan struct{}{InterruptListener(), InterruptListener(), InterruptListener()}
+	StartInterrupteListener()
+	waitListening(t)
+
+	for i, interrupted := range listeners {
+		select {
+		case <-interrupted:
+		case <-time.After(time.Second):
+			t.Fatalf("listener %d was not notified of shutdown request", i)
+		}
+	}
+	select {
+	case ev := <-events:
+		require.Nil(t, ev.Signal)
+		require.Contains(t, []string{"first", "second"}, ev.Reason)
+		require.False(t, ev.Repeated)
+		require.False(t, ev.Time.IsZero())
+	case <-time.After(time.Second):
+		t.Fatal("feed was not notified of shutdown request")
+	}
+	select {
+	case <-legacy:
+	case <-time.After(time.Second):
+		t.Fatal("legacy feed was not notified of shutdown request")
+	}
+	StartInterrupteListener()
+	select {
+	case <-events:
+		t.Fatal("feed notified more than once")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// A later request is reported as repeated, and a late listener
+	// observes the shutdown that already happened.
+	RequestShutdown("third")
+	select {
+	case ev := <-events:
+		require.Equal(t, "third", ev.Reason)
+		require.True(t, ev.Repeated)
+		require.Equal(t, 3, ev.Count, "the second request counts though it was never sent")
+	case <-time.After(time.Second):
+		t.Fatal("feed was not notified of repeated request")
+	}
+	late := InterruptListener()
+	select {
+	case <-late:
+	case <-time.After(time.Second):
+		t.Fatal("late listener was not notified of completed shutdown")
+	}
+	require.True(t, InterruptRequested(late))
+	require.True(t, InterruptRequested(done1))
+	require.True(t, InterruptRequested(done2))
+	require.False(t, InterruptRequested(released))
+	require.True(t, InterruptRequested(NewDoneChannel()))
+
+	sig, err := WaitForShutdownComplete(context.Background())
+	require.NoError(t, err)
+	require.Nil(t, sig)
+	require.True(t, handled.Load())
+	require.ErrorIs(t, RegisterShutdownHandler("late", 0, 0, nil), ErrShutdownInProgress)
+
+	<-ctx.Done()
+	require.ErrorIs(t, context.Cause(ctx), ErrShutdownRequested{})
+	var requested ErrShutdownRequested
+	require.ErrorAs(t, context.Cause(ctx), &requested)
+	require.Contains(t, []string{"first", "second"}, requested.Reason)
+	notifies, _ := n.counts()
+	require.Equal(t, 1, notifies)
+}
+
+func TestSimulateInterrupt(t *testing.T) {
+	resetSignals(t)
+	SetForceExitAfter(0)
+
+	ctx, cancel := WithInterrupt(context.Background())
+	defer cancel()
+	listeners := []<-chan struct{}{InterruptListener(), InterruptListener(), NewDoneChannel()}
+	events, sub := SubscribeInterrupt(2)
+	defer sub.Unsubscribe()
+	StartInterrupteListener()
+	repeated := make(chan int, 1)
+	OnRepeatedSignal(func(sig os.Signal, count int) { repeated <- count })
+
+	SimulateInterrupt(os.Interrupt)
+	for i, interrupted := range append(listeners, ctx.Done()) {
+		select {
+		case <-interrupted:
+		case <-time.After(time.Second):
+			t.Fatalf("listener %d was not notified of signal", i)
+		}
+	}
+	require.ErrorIs(t, context.Cause(ctx), ErrShutdownSignal{})
+	require.NotErrorIs(t, context.Cause(ctx), ErrShutdownRequested{})
+	var cause ErrShutdownSignal
+	require.ErrorAs(t, context.Cause(ctx), &cause)
+	require.Equal(t, os.Interrupt, cause.Signal)
+	select {
+	case ev := <-events:
+		require.Equal(t, os.Interrupt, ev.Signal)
+		require.Equal(t, "signal", ev.Reason)
+		require.False(t, ev.Repeated)
+		require.Equal(t, 1, ev.Count)
+	case <-time.After(time.Second):
+		t.Fatal("feed was not notified of signal")
+	}
+
+	SimulateInterrupt(os.Interrupt)
+	select {
+	case count := <-repeated:
+		require.Equal(t, 2, count)
+	case <-time.After(time.Second):
+		t.Fatal("repeated signal callback not called")
+	}
+	select {
+	case ev := <-events:
+		require.Equal(t, os.Interrupt, ev.Signal)
+		require.True(t, ev.Repeated)
+		require.Equal(t, 2, ev.Count)
+	case <-time.After(time.Second):
+		t.Fatal("feed was not notified of repeated signal")
+	}
+	require.Equal(t, os.Interrupt, WaitForShutdown())
+}
+
+func TestSlowInterruptSubscriber(t *testing.T) {
+	slowSubscriberWarning = 200 * time.Millisecond
+	t.Cleanup(func() { slowSubscriberWarning = time.Second })
+	resetSignals(t)
+	WithLogBuffer(16)
+	defer WithLogBuffer(0)
+
+	// Neither of these ever receives.
+	_, stuckSub := SubscribeInterruptNamed("stuck", 0)
+	defer stuckSub.Unsubscribe()
+	directSub := InterruptFeed.Subscribe(make(chan InterruptEvent))
+	defer directSub.Unsubscribe()
+	legacy := make(chan struct{})
+	legacySub := LegacyInterruptFeed.Subscribe(legacy)
+	defer legacySub.Unsubscribe()
+
+	a, subA := SubscribeInterrupt(0)
+	defer subA.Unsubscribe()
+	b, subB := SubscribeInterrupt(0)
+	defer subB.Unsubscribe()
+	StartInterrupteListener()
+
+	start := time.Now()
+	RequestShutdown("first")
+	RequestShutdown("second")
+	for _, reason := range []string{"first", "second"} {
+		for i, c := range []<-chan InterruptEvent{a, b} {
+			select {
+			case ev := <-c:
+				require.Equal(t, reason, ev.Reason)
+			case <-time.After(time.Second):
+				t.Fatalf("subscriber %d did not receive %s", i, reason)
+			}
+		}
+	}
+	select {
+	case <-legacy:
+	case <-time.After(time.Second):
+		t.Fatal("legacy feed was held up by a direct InterruptFeed subscriber")
+	}
+	require.Less(t, time.Since(start), slowSubscriberWarning)
+
+	var logs string
+	require.Eventually(t, func() bool {
+		logs = strings.Join(RecentLogLines(), "\n")
+		return strings.Contains(logs, "subscriber=stuck") && strings.Contains(logs, "subscriber=InterruptFeed")
+	}, time.Second, 10*time.Millisecond)
+	require.NotContains(t, logs, "signal_test.go")
+}
+
+func TestLateInterruptSubscriber(t *testing.T) {
+	resetSignals(t)
+
+	// No StartInterrupteListener: subscribing starts delivery.
+	early, earlySub := SubscribeInterrupt(1)
+	defer earlySub.Unsubscribe()
+	RequestShutdown("first")
+	select {
+	case ev := <-early:
+		require.Equal(t, "first", ev.Reason)
+	case <-time.After(time.Second):
+		t.Fatal("early subscriber did not receive the first event")
+	}
+
+	late, lateSub := SubscribeInterrupt(1)
+	defer lateSub.Unsubscribe()
+	select {
+	case ev := <-late:
+		require.Equal(t, "first", ev.Reason)
+		require.False(t, ev.Repeated)
+	case <-time.After(time.Second):
+		t.Fatal("late subscriber did not receive the first event")
+	}
+
+	RequestShutdown("second")
+	for _, c := range []<-chan InterruptEvent{early, late} {
+		select {
+		case ev := <-c:
+			require.Equal(t, "second", ev.Reason)
+			require.True(t, ev.Repeated)
+		case <-time.After(time.Second):
+			t.Fatal("subscriber did not receive the repeated event")
+		}
+	}
+}
+
+func TestResetForTesting(t *testing.T) {
+	n := resetSignals(t)
+
+	InterruptListener()
+	RequestShutdown("test")
+	require.True(t, InterruptRequested(NewDoneChannel()))
+	WaitForShutdown()
+
+	ResetForTesting()
+	_, stops := n.counts()
+	require.Equal(t, 1, stops)
+	require.False(t, InterruptRequested(NewDoneChannel()))
+	require.NoError(t, RegisterShutdownHandler("after-reset", 0, 0, func(context.Context) error { return nil }))
+	ok, reason := IsReady()
+	require.False(t, ok)
+	require.Equal(t, "starting", reason)
+}
+
+func TestSetInterruptSignals(t *testing.T) {
+	resetSignals(t)
+
+	require.Error(t, SetInterruptSignals(nil))
+	require.NoError(t, SetInterruptSignals([]os.Signal{os.Interrupt}))
+	InterruptListener()
+	require.Error(t, SetInterruptSignals([]os.Signal{os.Interrupt}))
+	require.Error(t, SetNotifier(new(fakeNotifier)))
+}
+
+func TestWithoutSignalHandling(t *testing.T) {
+	n := resetSignals(t)
+	WithoutSignalHandling()
+
+	interrupted := InterruptListener()
+	waitListening(t)
+	stop := ForwardSignalsTo(&exec.Cmd{Process: &os.Process{Pid: os.Getpid()}}, nil)
+	stop()
+	require.Error(t, ShutdownOnParentExit(nil))
+	notifies, _ := n.counts()
+	require.Zero(t, notifies)
+
+	// Shutdown can still be triggered programmatically.
+	RequestShutdown("test")
+	select {
+	case <-interrupted:
+	case <-time.After(time.Second):
+		t.Fatal("listener was not notified of shutdown request")
+	}
+}
+
+func TestForwardSignalsToUnstarted(t *testing.T) {
+	n := resetSignals(t)
+
+	stop := ForwardSignalsTo(exec.Command("true"), nil)
+	stop()
+	notifies, _ := n.counts()
+	require.Zero(t, notifies)
+}
+
+func TestShutdownWatchdog(t *testing.T) {
+	resetSignals(t)
+	SimulateInterrupt(os.Interrupt)
+
+	var out syncBuffer
+	exited := make(chan int, 2)
+	exit, stackDumpOutput = func(code int) { exited <- code }, &out
+	defer func() { exit, stackDumpOutput = os.Exit, os.Stderr }()
+	called := make(chan int, 2)
+	OnForceExit(func(code int) { called <- code })
+
+	ArmShutdownWatchdog(time.Hour)
+	DisarmShutdownWatchdog()
+	ArmShutdownWatchdog(time.Hour)
+	ArmShutdownWatchdog(0)
+
+	select {
+	case code := <-exited:
+		require.Equal(t, watchdogExitCode, code)
+	case <-time.After(time.Second):
+		t.Fatal("watchdog did not fire")
+	}
+	require.Equal(t, watchdogExitCode, <-called)
+	require.Contains(t, out.String(), "goroutine ")
+	DisarmShutdownWatchdog()
+
+	select {
+	case <-exited:
+		t.Fatal("replaced watchdog fired")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,33 @@
+//go:build unix
+
+package utils
+
+import (
+	"os"
+	"syscall"
+)
+
+// platformSignals are the signals besides os.Interrupt that trigger shutdown.
+// SIGTERM is what systemd and Kubernetes send to stop a process.  Catching
+// SIGQUIT replaces the Go runtime's default of dumping goroutines and exiting.
+var platformSignals = []os.Signal{syscall.SIGTERM, syscall.SIGQUIT}
+
+// reloadSignals are delivered to ReloadFeed instead of triggering shutdown.
+var reloadSignals = []os.Signal{syscall.SIGHUP}
+
+// pauseSignal and resumeSignal drive PauseFeed.
+var (
+	pauseSignal  os.Signal = syscall.SIGUSR1
+	resumeSignal os.Signal = syscall.SIGUSR2
+)
+
+// diagnosticsSignal is the default signal for EnableDiagnosticsSignal.
+// SIGVTALRM is otherwise unused by Go programs.
+var diagnosticsSignal os.Signal = syscall.SIGVTALRM
+
+func init() {
+	signalNames[syscall.SIGHUP] = "SIGHUP"
+	signalNames[syscall.SIGUSR1] = "SIGUSR1"
+	signalNames[syscall.SIGUSR2] = "SIGUSR2"
+	signalNames[syscall.SIGVTALRM] = "SIGVTALRM"
+}
@@ -0,0 +1,209 @@
+//go:build unix
+
+package utils
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const signalHelperEnv = "UTILS_SIGNAL_HELPER"
+
+// isSignalHelper reports whether the test runs as the child process started
+// by runSignalHelper.
+func isSignalHelper() bool {
+	return os.Getenv(signalHelperEnv) == "1"
+}
+
+// runSignalHelper re-runs the test named name in a child process so it gets
+// fresh package state and real signals don't reach this process.  Once the
+// child prints "ready" it is sent sig; the rest of its output is returned.
+func runSignalHelper(t *testing.T, name string, sig os.Signal) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, os.Args[0], "-test.run=^"+name+"$")
+	cmd.Env = append(os.Environ(), signalHelperEnv+"=1")
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Start())
+
+	r := bufio.NewReader(stdout)
+	line, err := r.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "ready\n", line)
+	require.NoError(t, cmd.Process.Signal(sig))
+
+	rest, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, cmd.Wait())
+	return string(rest)
+}
+
+func TestWithInterruptSignal(t *testing.T) {
+	if isSignalHelper() {
+		ctx := InterruptContext()
+		<-listening
+		fmt.Println("ready")
+		<-ctx.Done()
+		fmt.Println("cause:", context.Cause(ctx))
+		return
+	}
+
+	out := runSignalHelper(t, "TestWithInterruptSignal", syscall.SIGINT)
+	require.Contains(t, out, "cause: shutdown signal received: SIGINT")
+}
+
+func TestInterruptListenerSIGTERM(t *testing.T) {
+	if isSignalHelper() {
+		interrupted := InterruptListener()
+		<-listening
+		fmt.Println("ready")
+		sig := WaitForShutdown()
+		<-interrupted
+		fmt.Println("closed by", signalName(sig))
+		return
+	}
+
+	out := runSignalHelper(t, "TestInterruptListenerSIGTERM", syscall.SIGTERM)
+	require.Contains(t, out, "closed by SIGTERM")
+}
+
+func TestReloadSignal(t *testing.T) {
+	if isSignalHelper() {
+		interrupted := InterruptListener()
+		reloaded := make(chan struct{}, 1)
+		OnReload(func() { reloaded <- struct{}{} })
+		<-listening
+		fmt.Println("ready")
+
+		<-reloaded
+		fmt.Println("reloaded, interrupted:", InterruptRequested(interrupted))
+
+		// Once shutdown has begun SIGHUP is ignored.
+		RequestShutdown("test")
+		syscall.Kill(os.Getpid(), syscall.SIGHUP)
+		select {
+		case <-reloaded:
+			fmt.Println("reloaded during shutdown")
+		case <-time.After(100 * time.Millisecond):
+		}
+		return
+	}
+
+	out := runSignalHelper(t, "TestReloadSignal", syscall.SIGHUP)
+	require.Contains(t, out, "reloaded, interrupted: false")
+	require.NotContains(t, out, "reloaded during shutdown")
+}
+
+func TestInterruptEventSignal(t *testing.T) {
+	if isSignalHelper() {
+		events, sub := SubscribeInterrupt(1)
+		defer sub.Unsubscribe()
+		StartInterrupteListener()
+		<-listening
+		fmt.Println("ready")
+
+		ev := <-events
+		fmt.Printf("event: %s %s repeated=%v\n", signalName(ev.Signal), ev.Reason, ev.Repeated)
+		return
+	}
+
+	out := runSignalHelper(t, "TestInterruptEventSignal", syscall.SIGINT)
+	require.Contains(t, out, "event: SIGINT signal repeated=false")
+}
+
+func TestForceExitAfterRepeatedSignals(t *testing.T) {
+	if isSignalHelper() {
+		InterruptListener()
+		<-listening
+		for i := 0; i < 3; i++ {
+			syscall.Kill(os.Getpid(), syscall.SIGINT)
+			time.Sleep(50 * time.Millisecond)
+		}
+		time.Sleep(5 * time.Second)
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestForceExitAfterRepeatedSignals$")
+	cmd.Env = append(os.Environ(), signalHelperEnv+"=1")
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, 130, exitErr.ExitCode())
+	require.Contains(t, string(out), "goroutine ")
+}
+
+func TestForwardSignalsTo(t *testing.T) {
+	n := resetSignals(t)
+	SetIdleTimeout(5 * time.Second)
+
+	cmd := exec.Command("sleep", "10")
+	require.NoError(t, cmd.Start())
+	exited := make(chan struct{})
+	var waitErr error
+	go func() {
+		waitErr = cmd.Wait()
+		close(exited)
+	}()
+	stop := ForwardSignalsTo(cmd, exited)
+	defer stop()
+	waitListening(t)
+	notifies, _ := n.counts()
+	require.Equal(t, 2, notifies, "dispatcher and forwarder both go through the notifier")
+	work := DefaultTracker.Outstanding()
+	require.Len(t, work, 1)
+	require.Equal(t, fmt.Sprintf("child %d", cmd.Process.Pid), work[0].Name)
+
+	// The shutdown handlers only run once the drain has seen the child exit.
+	var childGone bool
+	require.NoError(t, RegisterShutdownHandler("check", 0, 0, func(context.Context) error {
+		childGone = InterruptRequested(exited)
+		return nil
+	}))
+	n.send(syscall.SIGTERM)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	sig, err := WaitForShutdownComplete(ctx)
+	require.NoError(t, err)
+	require.Equal(t, syscall.SIGTERM, sig, "the forwarded signal triggers shutdown here too")
+	require.True(t, childGone)
+
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, waitErr, &exitErr)
+	require.Equal(t, syscall.SIGTERM, exitErr.Sys().(syscall.WaitStatus).Signal())
+
+	// The forwarder stops once the child has exited.
+	require.Eventually(t, func() bool {
+		_, stops := n.counts()
+		return stops == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestForwardSignalsToStop(t *testing.T) {
+	n := resetSignals(t)
+
+	cmd := exec.Command("sleep", "10")
+	require.NoError(t, cmd.Start())
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+	stop := ForwardSignalsTo(cmd, make(chan struct{}))
+	require.Len(t, DefaultTracker.Outstanding(), 1)
+	stop()
+	stop()
+	require.Eventually(t, func() bool {
+		_, stops := n.counts()
+		return stops == 1 && len(DefaultTracker.Outstanding()) == 0
+	}, time.Second, 10*time.Millisecond)
+}
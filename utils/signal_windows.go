@@ -0,0 +1,22 @@
+//go:build windows
+
+package utils
+
+import "os"
+
+// platformSignals are the signals besides os.Interrupt that trigger shutdown.
+// The Go runtime delivers both CTRL_C_EVENT and CTRL_BREAK_EVENT as
+// os.Interrupt (there is no separate syscall.SIGBREAK), so nothing else is
+// needed for a break event to go through the same graceful path.
+var platformSignals []os.Signal
+
+// reloadSignals is empty because Windows has no SIGHUP.
+var reloadSignals []os.Signal
+
+// pauseSignal and resumeSignal are nil because Windows has no SIGUSR1 and
+// SIGUSR2; use RequestPause and RequestResume instead.
+var pauseSignal, resumeSignal os.Signal
+
+// diagnosticsSignal is nil because Windows has no spare signal; use
+// DumpDiagnostics instead.
+var diagnosticsSignal os.Signal
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Metrics reported to go-ethereum's registry while metrics.Enabled is set.
+// They are looked up with GetOrRegister when they change, so they are
+// registered once no matter how many listeners there are.
+//
+//	utils/interrupt/signals/<sig>  counter of interrupt signals by canonical name
+//	utils/shutdown/inprogress      gauge, 1 from the start of shutdown until the handlers finish
+//	utils/shutdown/duration        timer, from the start of shutdown until the handlers finish
+//	utils/process/start            gauge, process start time in Unix seconds
+//	utils/ready                    gauge, 1 while IsReady reports true
+const (
+	signalsMetricPrefix      = "utils/interrupt/signals/"
+	shutdownInProgressMetric = "utils/shutdown/inprogress"
+	shutdownDurationMetric   = "utils/shutdown/duration"
+	processStartMetric       = "utils/process/start"
+	readyMetric              = "utils/ready"
+)
+
+// processStart is when the package was initialized, close enough to the start
+// of the process for computing uptime.
+var processStart = time.Now()
+
+// ShutdownStats is what InterruptStats reports: the same values as the
+// metrics, for programs that don't run the metrics subsystem.
+type ShutdownStats struct {
+	Signals      map[string]int64 // interrupt signals received, by canonical name
+	InProgress   bool             // shutdown has begun and the handlers haven't finished
+	Duration     time.Duration    // of the finished shutdown handlers, zero until then
+	ProcessStart time.Time
+}
+
+var (
+	statsMu            sync.Mutex
+	signalCounts       = make(map[string]int64)
+	shutdownInProgress bool
+	shutdownDuration   time.Duration
+)
+
+// InterruptStats returns a snapshot of the interrupt and shutdown statistics.
+func InterruptStats() ShutdownStats {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	signals := make(map[string]int64, len(signalCounts))
+	for name, n := range signalCounts {
+		signals[name] = n
+	}
+	return ShutdownStats{
+		Signals:      signals,
+		InProgress:   shutdownInProgress,
+		Duration:     shutdownDuration,
+		ProcessStart: processStart,
+	}
+}
+
+// countSignal counts an interrupt signal.
+func countSignal(sig os.Signal) {
+	name := signalName(sig)
+
+	statsMu.Lock()
+	signalCounts[name]++
+	statsMu.Unlock()
+
+	metrics.GetOrRegisterCounter(signalsMetricPrefix+name, nil).Inc(1)
+}
+
+// shutdownBegan records that shutdown has begun.
+func shutdownBegan() {
+	statsMu.Lock()
+	shutdownInProgress = true
+	statsMu.Unlock()
+
+	metrics.GetOrRegisterGauge(shutdownInProgressMetric, nil).Update(1)
+	shutdownNotReady()
+}
+
+// shutdownFinished records that the shutdown handlers finished d after
+// shutdown began.
+func shutdownFinished(d time.Duration) {
+	statsMu.Lock()
+	shutdownInProgress = false
+	shutdownDuration = d
+	statsMu.Unlock()
+
+	metrics.GetOrRegisterGauge(shutdownInProgressMetric, nil).Update(0)
+	metrics.GetOrRegisterTimer(shutdownDurationMetric, nil).Update(d)
+}
+
+// registerProcessStart reports processStart.
+func registerProcessStart() {
+	metrics.GetOrRegisterGauge(processStartMetric, nil).Update(processStart.Unix())
+}
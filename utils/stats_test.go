@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterruptStats(t *testing.T) {
+	names := []string{signalsMetricPrefix + "SIGINT", shutdownInProgressMetric, shutdownDurationMetric, processStartMetric}
+	unregister := func() {
+		for _, name := range names {
+			metrics.Unregister(name)
+		}
+	}
+	// Other tests may have registered no-op metrics while metrics were
+	// disabled.
+	unregister()
+	metrics.Enabled = true
+	t.Cleanup(func() {
+		metrics.Enabled = false
+		unregister()
+	})
+	resetSignals(t)
+	SetForceExitAfter(0)
+
+	stats := InterruptStats()
+	require.Empty(t, stats.Signals)
+	require.False(t, stats.InProgress)
+	require.Equal(t, processStart, stats.ProcessStart)
+
+	// Registering again is harmless.
+	InterruptListener()
+	InterruptListener()
+	require.Equal(t, processStart.Unix(), metrics.GetOrRegisterGauge(processStartMetric, nil).Snapshot().Value())
+
+	// Hold shutdown open so the in-progress gauge can be observed.
+	release := make(chan struct{})
+	require.NoError(t, RegisterShutdownHandler("hold", 0, time.Second, func(ctx context.Context) error {
+		<-release
+		return nil
+	}))
+
+	SimulateInterrupt(os.Interrupt)
+	SimulateInterrupt(os.Interrupt)
+	require.Eventually(t, func() bool {
+		return InterruptStats().Signals["SIGINT"] == 2
+	}, time.Second, 10*time.Millisecond)
+	require.True(t, InterruptStats().InProgress)
+	require.EqualValues(t, 2, metrics.GetOrRegisterCounter(signalsMetricPrefix+"SIGINT", nil).Snapshot().Count())
+	require.EqualValues(t, 1, metrics.GetOrRegisterGauge(shutdownInProgressMetric, nil).Snapshot().Value())
+
+	close(release)
+	_, err := WaitForShutdownComplete(context.Background())
+	require.NoError(t, err)
+	stats = InterruptStats()
+	require.False(t, stats.InProgress)
+	require.EqualValues(t, 0, metrics.GetOrRegisterGauge(shutdownInProgressMetric, nil).Snapshot().Value())
+	require.EqualValues(t, 1, metrics.GetOrRegisterTimer(shutdownDurationMetric, nil).Snapshot().Count())
+}
+
+func TestInterruptStatsListenerOnly(t *testing.T) {
+	resetSignals(t)
+
+	InterruptListener()
+	SimulateInterrupt(os.Interrupt)
+	require.Eventually(t, func() bool {
+		stats := InterruptStats()
+		return !stats.InProgress && stats.Duration > 0
+	}, time.Second, 10*time.Millisecond)
+}
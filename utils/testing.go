@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"os"
+	"time"
+)
+
+// ResetForTesting returns the package to its initial state so that tests can
+// trigger shutdown more than once per process: it stops the dispatcher and
+// unregisters its signals, stops the goroutines delivering to the feeds,
+// forgets the shutdown, registered handlers, done channels, callbacks,
+// readiness, pause state, statistics and the work tracked by DefaultTracker,
+// disables the diagnostics signal, disarms the watchdog and restores the
+// default notifier, interrupt signals, idle timeout and force exit settings.
+// The logger and metrics registry are left alone.
+//
+// It must not be called while the package is in use from other goroutines.
+// Subscriptions to InterruptFeed, LegacyInterruptFeed, ReloadFeed and
+// PauseFeed are not touched and should be unsubscribed by the test that made
+// them.  A test typically calls it with t.Cleanup.
+func ResetForTesting() {
+	DisarmShutdownWatchdog()
+
+	diagnosticsMu.Lock()
+	if diagnosticsSignals != nil {
+		signalsMu.Lock()
+		notifier.Stop(diagnosticsSignals)
+		signalsMu.Unlock()
+	}
+	diagnosticsDir, diagnosticsSig, diagnosticsSignals = "", diagnosticsSignal, nil
+	diagnosticsMu.Unlock()
+
+	signalsMu.Lock()
+	if dispatcherSignals != nil {
+		notifier.Stop(dispatcherSignals)
+	}
+	notifier = osNotifier{}
+	interruptSignals = append([]os.Signal{os.Interrupt}, platformSignals...)
+	dispatcherStarted = false
+	dispatcherSignals = nil
+	listening = make(chan struct{})
+	signalsMu.Unlock()
+
+	workersMu.Lock()
+	close(workersQuit)
+	workersMu.Unlock()
+	workers.Wait()
+	workersMu.Lock()
+	workersQuit = make(chan struct{})
+	workersMu.Unlock()
+
+	shutdownMu.Lock()
+	shutdownChannel = make(chan struct{})
+	shutdownBegun.Store(false)
+	shutdownSignal, shutdownReason, shutdownStarted = nil, "", time.Time{}
+	shutdownMu.Unlock()
+
+	doneMu.Lock()
+	doneChannels = make(map[<-chan struct{}]chan struct{})
+	doneClosed = false
+	doneMu.Unlock()
+
+	handlersMu.Lock()
+	handlers = nil
+	handlersStarted = false
+	handlersDone = make(chan struct{})
+	handlersMu.Unlock()
+
+	statsMu.Lock()
+	signalCounts = make(map[string]int64)
+	shutdownInProgress, shutdownDuration = false, 0
+	statsMu.Unlock()
+
+	repeatedMu.Lock()
+	repeatedCallbacks = nil
+	repeatedMu.Unlock()
+
+	feedStarted.Store(false)
+	interruptCount.Store(0)
+	subscribersMu.Lock()
+	latchedInterrupt = nil
+	subscribersMu.Unlock()
+	drain(feedEvents)
+	drain(reloadRequests)
+	drain(pauseEvents)
+	paused.Store(false)
+
+	readyMu.Lock()
+	ready, readyReason = false, "starting"
+	readyChannel = make(chan struct{})
+	updateReadyGauge(0)
+	readyMu.Unlock()
+
+	markerLocksMu.Lock()
+	for path, f := range markerLocks {
+		f.Close()
+		delete(markerLocks, path)
+	}
+	markerLocksMu.Unlock()
+
+	DefaultTracker.reset()
+	idleTimeout.Store(0)
+	signalHandlingDisabled.Store(false)
+	suffixDuplicateHandlers.Store(false)
+	watchedParent.Store(0)
+	forceExitMu.Lock()
+	forceExitCallbacks = nil
+	forceExitMu.Unlock()
+	forceExitAfter.Store(defaultForceExitAfter)
+	forceExitCode.Store(defaultForceExitCode)
+}
+
+// drain discards whatever is buffered in c.
+func drain[T any](c chan T) {
+	for {
+		select {
+		case <-c:
+		default:
+			return
+		}
+	}
+}
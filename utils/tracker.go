@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Tracker keeps track of named in-flight work, so that shutdown can wait for
+// it and report what is holding it up.  Unlike a sync.WaitGroup every piece
+// of work has a name and an age.  It is safe for concurrent use.
+type Tracker struct {
+	mu     sync.Mutex
+	next   uint64
+	tokens map[uint64]*Token
+	idle   chan struct{} // closed once tokens is empty, nil unless awaited
+}
+
+// Token stands for one piece of work added to a Tracker.
+type Token struct {
+	t     *Tracker
+	id    uint64
+	name  string
+	added time.Time
+	done  atomic.Bool
+}
+
+// DefaultTracker is the Tracker used by AwaitIdle and SetIdleTimeout.
+var DefaultTracker = NewTracker()
+
+// NewTracker returns an empty Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{tokens: make(map[uint64]*Token)}
+}
+
+// Add tracks a new piece of work under name until the returned token's Done
+// is called.  Once shutdown has begun it returns ErrShutdownInProgress, so
+// that callers can stop accepting work.
+func (t *Tracker) Add(name string) (*Token, error) {
+	if shutdownBegun.Load() {
+		return nil, ErrShutdownInProgress
+	}
+	tok := &Token{t: t, name: name, added: time.Now()}
+
+	t.mu.Lock()
+	t.next++
+	tok.id = t.next
+	t.tokens[tok.id] = tok
+	t.mu.Unlock()
+	return tok, nil
+}
+
+// Done releases the token.  Calling it more than once has no further effect.
+func (tok *Token) Done() {
+	if !tok.done.CompareAndSwap(false, true) {
+		return
+	}
+	t := tok.t
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.tokens, tok.id)
+	if len(t.tokens) == 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// OutstandingWork describes a token that hasn't been released.
+type OutstandingWork struct {
+	Name string
+	Age  time.Duration
+}
+
+// Outstanding returns the unreleased tokens, oldest first.
+func (t *Tracker) Outstanding() []OutstandingWork {
+	now := time.Now()
+
+	t.mu.Lock()
+	tokens := make([]*Token, 0, len(t.tokens))
+	for _, tok := range t.tokens {
+		tokens = append(tokens, tok)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].id < tokens[j].id })
+	work := make([]OutstandingWork, len(tokens))
+	for i, tok := range tokens {
+		work[i] = OutstandingWork{Name: tok.name, Age: now.Sub(tok.added)}
+	}
+	return work
+}
+
+// NotIdleError is returned by AwaitIdle when ctx is done before every token
+// was released.
+type NotIdleError struct {
+	Outstanding []OutstandingWork // oldest first
+	Err         error             // ctx.Err()
+}
+
+func (e *NotIdleError) Error() string {
+	work := make([]string, len(e.Outstanding))
+	for i, w := range e.Outstanding {
+		work[i] = fmt.Sprintf("%s (%v)", w.Name, w.Age.Round(time.Millisecond))
+	}
+	return fmt.Sprintf("%v: %d still in flight: %s", e.Err, len(work), strings.Join(work, ", "))
+}
+
+func (e *NotIdleError) Unwrap() error { return e.Err }
+
+// AwaitIdle blocks until every token has been released, returning nil, or
+// until ctx is done.  In that case the unreleased tokens are logged and
+// returned in a *NotIdleError, which wraps ctx.Err().
+func (t *Tracker) AwaitIdle(ctx context.Context) error {
+	for {
+		t.mu.Lock()
+		if len(t.tokens) == 0 {
+			t.mu.Unlock()
+			return nil
+		}
+		if t.idle == nil {
+			t.idle = make(chan struct{})
+		}
+		idle := t.idle
+		t.mu.Unlock()
+
+		select {
+		case <-idle:
+			// Work may have been added since; check again.
+		case <-ctx.Done():
+			err := &NotIdleError{Outstanding: t.Outstanding(), Err: ctx.Err()}
+			for _, w := range err.Outstanding {
+				logShutdown("work still in flight", "phase", "drain", "work", w.Name, "age", w.Age)
+			}
+			if len(err.Outstanding) == 0 {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// reset forgets all tokens.
+func (t *Tracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.tokens = make(map[uint64]*Token)
+	if t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// AwaitIdle waits for DefaultTracker to become idle; see Tracker.AwaitIdle.
+func AwaitIdle(ctx context.Context) error {
+	return DefaultTracker.AwaitIdle(ctx)
+}
+
+var idleTimeout atomic.Int64
+
+// SetIdleTimeout makes shutdown wait up to d for DefaultTracker to become idle
+// before the shutdown handlers run.  Work still in flight after d is logged
+// and the handlers run anyway.  The default of 0 doesn't wait.
+func SetIdleTimeout(d time.Duration) {
+	idleTimeout.Store(int64(d))
+}
+
+// awaitIdleBeforeHandlers waits as configured with SetIdleTimeout.
+func awaitIdleBeforeHandlers() {
+	d := time.Duration(idleTimeout.Load())
+	if d <= 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	DefaultTracker.AwaitIdle(ctx)
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackerStaleTokens(t *testing.T) {
+	resetSignals(t)
+	tr := NewTracker()
+
+	writer, err := tr.Add("trace-writer")
+	require.NoError(t, err)
+	sub, err := tr.Add("rpc-subscription")
+	require.NoError(t, err)
+	done, err := tr.Add("done")
+	require.NoError(t, err)
+	done.Done()
+	done.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = tr.AwaitIdle(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	var notIdle *NotIdleError
+	require.ErrorAs(t, err, &notIdle)
+	require.Len(t, notIdle.Outstanding, 2)
+	require.Equal(t, "trace-writer", notIdle.Outstanding[0].Name)
+	require.Equal(t, "rpc-subscription", notIdle.Outstanding[1].Name)
+	require.GreaterOrEqual(t, notIdle.Outstanding[0].Age, 20*time.Millisecond)
+	require.ErrorContains(t, err, "2 still in flight: trace-writer (")
+
+	writer.Done()
+	sub.Done()
+	require.NoError(t, tr.AwaitIdle(context.Background()))
+}
+
+func TestTrackerRejectsAfterShutdown(t *testing.T) {
+	resetSignals(t)
+	tr := NewTracker()
+
+	tok, err := tr.Add("before")
+	require.NoError(t, err)
+	RequestShutdown("test")
+	_, err = tr.Add("after")
+	require.ErrorIs(t, err, ErrShutdownInProgress)
+
+	// Work added before shutdown is still waited for.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		tok.Done()
+	}()
+	require.NoError(t, tr.AwaitIdle(context.Background()))
+}
+
+func TestIdleBeforeHandlers(t *testing.T) {
+	resetSignals(t)
+	SetIdleTimeout(time.Second)
+
+	tok, err := DefaultTracker.Add("batch")
+	require.NoError(t, err)
+	var idle bool
+	require.NoError(t, RegisterShutdownHandler("check", 0, 0, func(context.Context) error {
+		idle = len(DefaultTracker.Outstanding()) == 0
+		return nil
+	}))
+
+	RequestShutdown("test")
+	time.Sleep(10 * time.Millisecond)
+	tok.Done()
+	WaitForShutdown()
+	require.True(t, idle)
+}
+
+func TestTrackerConcurrent(t *testing.T) {
+	resetSignals(t)
+	tr := NewTracker()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5000; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tok, err := tr.Add("work-" + strconv.Itoa(i%10))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if i%3 == 0 {
+				tr.Outstanding()
+			}
+			tok.Done()
+		}(i)
+	}
+	awaited := make(chan error, 1)
+	go func() { awaited <- tr.AwaitIdle(context.Background()) }()
+	wg.Wait()
+
+	select {
+	case err := <-awaited:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("tracker did not become idle")
+	}
+	require.Empty(t, tr.Outstanding())
+}
+
+func BenchmarkTrackerAddDone(b *testing.B) {
+	tr := NewTracker()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			tok, err := tr.Add("block")
+			if err != nil {
+				b.Fatal(err)
+			}
+			tok.Done()
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// watchdogExitCode is the exit code used when the shutdown watchdog fires.
+const watchdogExitCode = 2
+
+var (
+	watchdogMu   sync.Mutex
+	watchdogStop chan struct{}
+)
+
+// ArmShutdownWatchdog makes sure the process doesn't outlive a hung graceful
+// shutdown: if it is still running d after shutdown began, the watchdog logs
+// an error, writes all goroutine stacks to stderr and exits with code 2.
+// Arming it again replaces the previous deadline; it may be armed before or
+// after shutdown has begun.
+func ArmShutdownWatchdog(d time.Duration) {
+	watchdogMu.Lock()
+	defer watchdogMu.Unlock()
+
+	if watchdogStop != nil {
+		close(watchdogStop)
+	}
+	stop := make(chan struct{})
+	watchdogStop = stop
+
+	done := shutdownDone()
+	go func() {
+		select {
+		case <-done:
+		case <-stop:
+			return
+		}
+
+		_, _, started := shutdownState()
+		timer := time.NewTimer(time.Until(started.Add(d)))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-stop:
+			return
+		}
+
+		logShutdownAt(logger().Error, "graceful shutdown timed out, exiting", "deadline", d, "elapsed", time.Since(started), "code", watchdogExitCode)
+		dumpGoroutines(stackDumpOutput)
+		forceExit(watchdogExitCode)
+	}()
+}
+
+// DisarmShutdownWatchdog stops a watchdog started with ArmShutdownWatchdog.
+func DisarmShutdownWatchdog() {
+	watchdogMu.Lock()
+	defer watchdogMu.Unlock()
+
+	if watchdogStop != nil {
+		close(watchdogStop)
+		watchdogStop = nil
+	}
+}